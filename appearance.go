@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Appearances that can be configured.
+const (
+	autoAppearance  = "auto"
+	darkAppearance  = "dark"
+	lightAppearance = "light"
+)
+
+// defaultLightTheme is the theme that uses the colors of the terminal on a
+// light background.
+const defaultLightTheme = "default-light"
+
+var (
+	backgroundOnce sync.Once
+	darkBackground bool
+)
+
+// hasDarkBackground reports whether the terminal has a dark background. The
+// terminal is only asked once, as it may take a while to answer.
+func hasDarkBackground() bool {
+	backgroundOnce.Do(func() {
+		darkBackground = termenv.HasDarkBackground()
+	})
+	return darkBackground
+}
+
+// adaptToTerminal returns the config adapted to the terminal. The color
+// profile of the terminal decides how many colors are used, or none if
+// NO_COLOR is set, and the light theme, if there is one, is used on a light
+// background.
+func (c Config) adaptToTerminal() Config {
+	c.profile = termenv.EnvColorProfile()
+	lipgloss.SetColorProfile(c.profile)
+
+	dark := c.Appearance != lightAppearance
+	if c.Appearance == "" || c.Appearance == autoAppearance {
+		dark = c.profile == termenv.Ascii || hasDarkBackground()
+	}
+	lipgloss.SetHasDarkBackground(dark)
+	if dark || c.lightTheme() == "" {
+		return c
+	}
+	if light, err := c.withTheme(c.lightTheme()); err == nil {
+		return light
+	}
+	return c
+}
+
+// lightTheme returns the theme for a light background: the configured light
+// theme, or the default light theme if no theme was configured at all.
+func (c Config) lightTheme() string {
+	if c.LightTheme == "" && c.Theme == defaultTheme {
+		return defaultLightTheme
+	}
+	return c.LightTheme
+}
+
+// chromaFormatter returns the chroma formatter for the color profile, or an
+// empty string if there are no colors.
+func (c Config) chromaFormatter() string {
+	switch c.profile {
+	case termenv.TrueColor:
+		return "terminal16m"
+	case termenv.ANSI256:
+		return "terminal256"
+	case termenv.ANSI:
+		return "terminal16"
+	}
+	return ""
+}
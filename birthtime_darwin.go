@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime returns the time the file at the path was created.
+func birthTime(path string, fi os.FileInfo) time.Time {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fi.ModTime()
+	}
+	return time.Unix(st.Birthtimespec.Unix())
+}
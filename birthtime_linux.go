@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// birthTime returns the time the file at the path was created, or the time
+// it was last modified when the file system does not record it.
+func birthTime(path string, fi os.FileInfo) time.Time {
+	var stx unix.Statx_t
+	err := unix.Statx(unix.AT_FDCWD, path, 0, unix.STATX_BTIME, &stx)
+	if err != nil || stx.Mask&unix.STATX_BTIME == 0 {
+		return fi.ModTime()
+	}
+	return time.Unix(stx.Btime.Sec, int64(stx.Btime.Nsec))
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// birthTime returns the time the file at the path was last modified, as
+// creation times are not available on this system.
+func birthTime(path string, fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// birthTime returns the time the file at the path was created.
+func birthTime(path string, fi os.FileInfo) time.Time {
+	data, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return fi.ModTime()
+	}
+	return time.Unix(0, data.CreationTime.Nanoseconds())
+}
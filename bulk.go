@@ -0,0 +1,263 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+var errNoLanguage = errors.New("no language given")
+
+// toggleMark marks the selected snippet, or unmarks it if it was marked, and
+// moves on to the next snippet.
+func (m *Model) toggleMark() {
+	if m.List().SelectedItem() == nil {
+		return
+	}
+	s := m.selectedSnippet()
+	key := metadataKey(s.Folder, s.File)
+	if _, ok := m.marked[key]; ok {
+		delete(m.marked, key)
+	} else {
+		m.marked[key] = s
+	}
+	m.List().CursorDown()
+}
+
+// clearMarks unmarks all snippets.
+func (m *Model) clearMarks() {
+	for key := range m.marked {
+		delete(m.marked, key)
+	}
+}
+
+// targets returns the snippets that an action applies to, which are the
+// marked snippets or else the selected snippet.
+func (m *Model) targets() []Snippet {
+	if len(m.marked) <= 0 {
+		if m.List().SelectedItem() == nil {
+			return nil
+		}
+		return []Snippet{m.selectedSnippet()}
+	}
+
+	var snippets []Snippet
+	folders := maps.Keys(m.Lists)
+	slices.Sort(folders)
+	for _, folder := range folders {
+		for _, item := range m.Lists[folder].Items() {
+			s, ok := item.(Snippet)
+			if !ok {
+				continue
+			}
+			if _, ok := m.marked[metadataKey(s.Folder, s.File)]; ok {
+				snippets = append(snippets, s)
+			}
+		}
+	}
+	return snippets
+}
+
+// replaceSnippet replaces the snippet in the lists with its new version,
+// moving it to the list of its new folder if needed.
+func (m *Model) replaceSnippet(old, s Snippet) tea.Cmd {
+	src, ok := m.Lists[Folder(old.Folder)]
+	i := -1
+	if ok {
+		i = indexOfSnippet(src, old)
+	}
+	if old.Folder == s.Folder && i >= 0 {
+		return src.SetItem(i, s)
+	}
+	if i >= 0 {
+		src.RemoveItem(i)
+	}
+	dst, ok := m.Lists[Folder(s.Folder)]
+	if !ok {
+		dst = newList([]list.Item{}, m.height, m.ListStyle)
+		m.Lists[Folder(s.Folder)] = dst
+	}
+	return dst.InsertItem(len(dst.Items()), s)
+}
+
+// deleteSnippets moves the target snippets to the trash and removes them from
+// the lists.
+func (m *Model) deleteSnippets() tea.Cmd {
+	targets := m.targets()
+	m.clearMarks()
+	for _, s := range targets {
+		if err := trashSnippetFile(m.config.Root, s); err != nil {
+			m.displayError(err.Error())
+			return nil
+		}
+	}
+	return m.removeSnippets(targets)
+}
+
+// targetsContent returns the contents of the target snippets, separated by
+// blank lines.
+func (m *Model) targetsContent() (string, error) {
+	var contents []string
+	for _, s := range m.targets() {
+		content, err := m.readSnippet(s)
+		if err != nil {
+			return "", err
+		}
+		contents = append(contents, strings.TrimRight(content, "\n"))
+	}
+	return strings.Join(contents, "\n\n") + "\n", nil
+}
+
+// submitBulkPrompt applies the action with the value that was entered to all
+// target snippets.
+func (m *Model) submitBulkPrompt(action state, value string) tea.Cmd {
+	targets := m.targets()
+	m.clearMarks()
+
+	if action == exportingState {
+		return m.exportTargets(value, targets)
+	}
+
+	var (
+		cmds []tea.Cmd
+		err  error
+	)
+	for _, s := range targets {
+		updated := s
+		switch action {
+		case movingState:
+			updated, err = moveSnippetFile(m.config.Root, s, value, s.File, suffixOnCollision)
+		case retypingState:
+			if value == "" {
+				err = errNoLanguage
+				break
+			}
+			updated, err = moveSnippetFile(m.config.Root, s, s.Folder, s.fileName(s.Name, value), suffixOnCollision)
+		case taggingState:
+			updated.Tags = addTags(s.Tags, parseTags(value))
+			err = setTags(m.config.Root, updated)
+		case untaggingState:
+			updated.Tags = removeTags(s.Tags, parseTags(value))
+			err = setTags(m.config.Root, updated)
+		}
+		if err != nil {
+			break
+		}
+		cmds = append(cmds, m.replaceSnippet(s, updated))
+	}
+
+	cmds = append(cmds, m.updateFolders())
+	if err != nil {
+		m.displayError(err.Error())
+		return tea.Batch(cmds...)
+	}
+	return tea.Batch(append(cmds, m.updateContent())...)
+}
+
+// pendingExport is an export of snippets that waits for the user to decide
+// whether the encrypted snippets among them are exported too.
+type pendingExport struct {
+	dir       string
+	snippets  []Snippet
+	encrypted int
+}
+
+// exportTargets exports the target snippets into the directory. Encrypted
+// snippets are exported when selected on their own, and otherwise the user is
+// asked whether to export them along.
+func (m *Model) exportTargets(dir string, targets []Snippet) tea.Cmd {
+	encrypted := 0
+	for _, s := range targets {
+		if s.Encrypted {
+			encrypted++
+		}
+	}
+	if encrypted > 0 && len(targets) > 1 {
+		m.pendingExport = &pendingExport{dir: dir, snippets: targets, encrypted: encrypted}
+		m.state = confirmingExportState
+		m.pane = snippetPane
+		m.updateKeyMap()
+		return nil
+	}
+	if _, err := exportSnippets(m.config.Root, dir, targets, true); err != nil {
+		m.displayError(err.Error())
+	}
+	return nil
+}
+
+// resolveExport finishes the pending export, with its encrypted snippets if
+// the user chose to, and reports the snippets that were left out.
+func (m *Model) resolveExport(encrypted bool) tea.Cmd {
+	export := m.pendingExport
+	m.pendingExport = nil
+	m.state = navigatingState
+	m.updateKeyMap()
+	if export == nil {
+		return nil
+	}
+	skipped, err := exportSnippets(m.config.Root, export.dir, export.snippets, encrypted)
+	switch {
+	case err != nil:
+		m.displayError(err.Error())
+	case skipped == 1:
+		m.displayError("Left out 1 encrypted snippet")
+	case skipped > 1:
+		m.displayError(fmt.Sprintf("Left out %d encrypted snippets", skipped))
+	}
+	return nil
+}
+
+// exportSnippets copies the snippet files into the directory, keeping them in
+// their folders. Encrypted snippets are skipped unless asked for, and are
+// copied as they are, still encrypted.
+//
+// It returns the number of encrypted snippets that were skipped.
+func exportSnippets(root, dir string, snippets []Snippet, encrypted bool) (int, error) {
+	if dir == "" {
+		return 0, fmt.Errorf("no export directory given")
+	}
+	skipped := 0
+	for _, s := range snippets {
+		if s.Encrypted && !encrypted {
+			skipped++
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(root, s.Folder, s.File))
+		if err != nil {
+			return skipped, err
+		}
+		if err := os.MkdirAll(filepath.Join(dir, s.Folder), 0755); err != nil {
+			return skipped, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, s.Folder, s.File), content, 0644); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, nil
+}
+
+// bulkPromptLabels are the labels of the prompts for actions on the target
+// snippets.
+var bulkPromptLabels = map[state]string{
+	movingState:    "Move to: ",
+	retypingState:  "Language: ",
+	taggingState:   "Add tag: ",
+	untaggingState: "Remove tag: ",
+	exportingState: "Export to: ",
+}
+
+// tagsString returns the tags as a string of hashtags.
+func tagsString(tags []string) string {
+	var s []string
+	for _, t := range tags {
+		s = append(s, "#"+t)
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultFormatters are the command templates that snippets of well-known
+// languages are formatted in place with, if the formatter is installed.
+var defaultFormatters = map[string]string{
+	"go":   "gofmt -w {file}",
+	"sh":   "shfmt -w {file}",
+	"bash": "shfmt -w {file}",
+}
+
+// defaultLinters are the command templates that snippets of well-known
+// languages are linted with, if the linter is installed.
+var defaultLinters = map[string]string{
+	"sh":   "shellcheck -f gcc {file}",
+	"bash": "shellcheck -f gcc {file}",
+	"py":   "pyflakes {file}",
+}
+
+// diagnosticPattern matches the problems that formatters and linters report,
+// written as file:line:column: message or file:line: message.
+var diagnosticPattern = regexp.MustCompile(`^(.+?):(\d+):(?:(\d+):)?\s*(.+)$`)
+
+// diagnostic is a problem that a formatter or linter reported for a line of a
+// snippet.
+type diagnostic struct {
+	line    int
+	column  int
+	message string
+}
+
+// String returns the line:column: message of the diagnostic.
+func (d diagnostic) String() string {
+	if d.column > 0 {
+		return fmt.Sprintf("%d:%d: %s", d.line, d.column, d.message)
+	}
+	return fmt.Sprintf("%d: %s", d.line, d.message)
+}
+
+// parseDiagnostics returns the diagnostics for the file in the output of a
+// formatter or linter, sorted by line.
+func parseDiagnostics(output, path string) []diagnostic {
+	var diagnostics []diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		match := diagnosticPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil || filepath.Base(match[1]) != filepath.Base(path) {
+			continue
+		}
+		n, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		diagnostics = append(diagnostics, diagnostic{n, column, match[4]})
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].line < diagnostics[j].line
+	})
+	return diagnostics
+}
+
+// toolCommand returns the command of the configured or well-known tool for
+// the language that works on the file. It returns nil if there is no tool,
+// if the tool is configured as an empty command or if a well-known tool is
+// not installed.
+func toolCommand(configured, defaults map[string]string, language, path string) *exec.Cmd {
+	t, ok := configured[language]
+	if !ok {
+		t = defaults[language]
+	}
+	args := splitCommand(t)
+	if len(args) <= 0 {
+		return nil
+	}
+	if _, ok := configured[language]; !ok {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			return nil
+		}
+	}
+	hasFile := false
+	for i, arg := range args {
+		hasFile = hasFile || strings.Contains(arg, "{file}")
+		args[i] = strings.ReplaceAll(arg, "{file}", path)
+	}
+	if !hasFile {
+		args = append(args, path)
+	}
+	return exec.Command(args[0], args[1:]...)
+}
+
+// runTool runs the command, returning the diagnostics for the file that it
+// reported. Exiting with an error is only an error if nothing was reported.
+func runTool(cmd *exec.Cmd, path string) ([]diagnostic, error) {
+	if cmd == nil {
+		return nil, nil
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	diagnostics := parseDiagnostics(out.String(), path)
+	var exitErr *exec.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || len(diagnostics) <= 0) {
+		msg := strings.TrimSpace(out.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("%s: %s", filepath.Base(cmd.Path), msg)
+	}
+	return diagnostics, nil
+}
+
+// formatSnippet formats the snippet file in place with the formatter for its
+// language. It reports whether the file changed, along with the problems that
+// kept the formatter from formatting it. Encrypted snippets are not formatted.
+func formatSnippet(config Config, s Snippet) (bool, []diagnostic, error) {
+	path := filepath.Join(config.Root, s.Folder, s.File)
+	cmd := toolCommand(config.Check.Formatters, defaultFormatters, s.Language, path)
+	if cmd == nil || s.Encrypted {
+		return false, nil, nil
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil, err
+	}
+	diagnostics, err := runTool(cmd, path)
+	if err != nil {
+		return false, nil, err
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		return false, nil, err
+	}
+	return !bytes.Equal(before, after), diagnostics, nil
+}
+
+// lintSnippet lints the snippet file with the linter for its language.
+// Encrypted snippets are not linted.
+func lintSnippet(config Config, s Snippet) ([]diagnostic, error) {
+	if s.Encrypted {
+		return nil, nil
+	}
+	path := filepath.Join(config.Root, s.Folder, s.File)
+	return runTool(toolCommand(config.Check.Linters, defaultLinters, s.Language, path), path)
+}
+
+// checkSnippet formats and lints the snippet, returning all the problems that
+// were found.
+func checkSnippet(config Config, s Snippet) ([]diagnostic, error) {
+	_, diagnostics, err := formatSnippet(config, s)
+	if err != nil {
+		return nil, err
+	}
+	linted, err := lintSnippet(config, s)
+	if err != nil {
+		return nil, err
+	}
+	diagnostics = append(diagnostics, linted...)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].line < diagnostics[j].line
+	})
+	return diagnostics, nil
+}
+
+// checkedMsg tells the application the problems that were found in a
+// snippet.
+type checkedMsg struct {
+	snippet     Snippet
+	diagnostics []diagnostic
+}
+
+// checkEditedSnippet returns a Cmd that formats and lints the snippet after it
+// was edited, if configured to.
+func (m *Model) checkEditedSnippet(s Snippet) tea.Cmd {
+	if !m.config.Check.OnSave {
+		return nil
+	}
+	return func() tea.Msg {
+		diagnostics, err := checkSnippet(m.config, s)
+		if err != nil {
+			return errorMsg{err}
+		}
+		return checkedMsg{s, diagnostics}
+	}
+}
+
+// setDiagnostics remembers the problems of the snippet to show them next to
+// the line numbers of the content pane.
+func (m *Model) setDiagnostics(msg checkedMsg) tea.Cmd {
+	key := metadataKey(msg.snippet.Folder, msg.snippet.File)
+	if len(msg.diagnostics) > 0 {
+		m.diagnostics[key] = msg.diagnostics
+	} else {
+		delete(m.diagnostics, key)
+	}
+	if s := m.selectedSnippet(); s.Folder == msg.snippet.Folder && s.File == msg.snippet.File {
+		return m.updateContent()
+	}
+	return nil
+}
+
+// problemsString returns the number of problems as a string.
+func problemsString(n int) string {
+	if n == 1 {
+		return "1 problem"
+	}
+	return fmt.Sprintf("%d problems", n)
+}
+
+// snippetsString returns the number of snippets as a string.
+func snippetsString(n int) string {
+	if n == 1 {
+		return "1 snippet"
+	}
+	return fmt.Sprintf("%d snippets", n)
+}
+
+// previewedDiagnostics returns the problems of the snippet in the content
+// pane, which are hidden along with masked snippets.
+func (m *Model) previewedDiagnostics() []diagnostic {
+	if m.rendersMarkdown() || m.masked() {
+		return nil
+	}
+	return m.diagnostics[metadataKey(m.previewed.Folder, m.previewed.File)]
+}
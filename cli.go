@@ -0,0 +1,826 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"golang.org/x/term"
+)
+
+// errUsage is returned by commands after printing their usage.
+var errUsage = errors.New("usage")
+
+// parseArgs parses the flags of a command, allowing flags to appear between
+// the positional arguments. Everything after "--" is positional.
+// It returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) <= 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// exitWithError prints the error of the command and exits. A command that
+// failed running a process exits with the exit code of the process.
+func exitWithError(err error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if !errors.Is(err, flag.ErrHelp) && !errors.Is(err, errUsage) {
+		fmt.Fprintln(os.Stderr, "snp:", err)
+	}
+	os.Exit(1)
+}
+
+// resolveSnippet returns the snippet that is exactly referred to by the name,
+// given as folder/name.ext, folder/name or name.ext.
+func resolveSnippet(name string, snippets []Snippet) (Snippet, error) {
+	var matches []Snippet
+	for _, s := range snippets {
+		switch name {
+		case s.String(), s.Folder + "/" + s.File, s.Folder + "/" + s.Name, s.File:
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Snippet{}, fmt.Errorf("no such snippet: %s", name)
+	case 1:
+		return matches[0], nil
+	}
+	return Snippet{}, fmt.Errorf("%s is ambiguous, use folder/name.ext", name)
+}
+
+// moveTarget returns the folder and file name that the snippet should be moved
+// to. A target ending in a slash is a folder, and a target without an
+// extension keeps the language of the snippet.
+//
+// A target without a slash keeps the folder of the snippet.
+//
+// Example, for the snippet Code/Hello.go:
+//
+//	Notes/         -> (Notes, Hello.go)
+//	Notes/Bye      -> (Notes, Bye.go)
+//	Notes/Bye.sh   -> (Notes, Bye.sh)
+//	Bye.sh         -> (Code, Bye.sh)
+//
+// Encrypted snippets stay encrypted.
+func moveTarget(to string, s Snippet) (string, string) {
+	folder := s.Folder
+	if i := strings.LastIndex(to, "/"); i >= 0 {
+		folder = to[:i]
+		to = to[i+1:]
+	}
+	if to == "" {
+		return folder, s.File
+	}
+	if !strings.Contains(to, ".") {
+		return folder, s.fileName(to, s.Language)
+	}
+	if s.Encrypted && !strings.HasSuffix(to, encryptedExt) {
+		to += encryptedExt
+	}
+	return folder, to
+}
+
+// moveCommand moves a snippet to another folder or name. A target without a
+// slash that names an existing folder moves the snippet into the folder.
+//
+//	snp mv [--force | --suffix] <from> <to>
+func moveCommand(config Config, snippets []Snippet, args []string) error {
+	fs := flag.NewFlagSet("mv", flag.ContinueOnError)
+	force := fs.Bool("force", false, "overwrite the snippet at the target")
+	suffix := fs.Bool("suffix", false, "add a numbered suffix if the target is taken")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp mv [--force | --suffix] <from> <to>")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 || (*force && *suffix) {
+		fs.Usage()
+		return errUsage
+	}
+
+	s, err := resolveSnippet(args[0], snippets)
+	if err != nil {
+		return err
+	}
+	c := failOnCollision
+	if *force {
+		c = overwriteOnCollision
+	} else if *suffix {
+		c = suffixOnCollision
+	}
+
+	to := args[1]
+	// A target naming an existing folder moves the snippet into it, rather
+	// than renaming it in its own folder.
+	if !strings.Contains(to, "/") && folderExists(config.Root, to) {
+		to += "/"
+	}
+	folder, file := moveTarget(to, s)
+	moved, err := moveSnippetFile(config.Root, s, folder, file, c)
+	if errors.Is(err, errSnippetExists) {
+		return fmt.Errorf("%w, use --force to overwrite or --suffix to keep both", err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s -> %s\n", s, moved)
+	return nil
+}
+
+// listCommand prints the snippets, sorted by the sort mode if one is given.
+//
+//	snp list [--sort <mode>]
+func listCommand(config Config, snippets []Snippet, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	sort := fs.String("sort", "", "sort by "+strings.Join(sortNames(), ", "))
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp list [--sort <mode>]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	if *sort != "" {
+		mode, err := parseSort(*sort)
+		if err != nil {
+			return err
+		}
+		sortSnippets(snippets, mode, config)
+	}
+	for _, s := range snippets {
+		fmt.Println(s)
+	}
+	return nil
+}
+
+// showCommand prints the contents of a snippet, rendering Markdown snippets
+// as formatted documents if asked to.
+//
+//	snp show [--render] <name>
+func showCommand(config Config, snippets []Snippet, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	render := fs.Bool("render", false, "render Markdown snippets as formatted documents")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp show [--render] <name>")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	s, err := resolveSnippet(args[0], snippets)
+	if err != nil {
+		if s = findSnippet(args[0], snippets); s.File == "" {
+			return err
+		}
+	}
+	_ = recordUse(config.StateDir, s)
+
+	tty := isatty.IsTerminal(os.Stdout.Fd())
+	if !*render || !config.isMarkdown(s) {
+		content, err := s.Content(tty)
+		if err != nil {
+			return err
+		}
+		fmt.Print(content)
+		return nil
+	}
+	content, err := s.Content(false)
+	if err != nil {
+		return err
+	}
+	width := 80
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		width = w
+	}
+	rendered, err := renderMarkdown(content, width, config)
+	if err != nil {
+		return err
+	}
+	fmt.Println(rendered)
+	return nil
+}
+
+// valuesFlag collects the values of placeholders given as name=value.
+type valuesFlag map[string]string
+
+func (v valuesFlag) String() string { return "" }
+
+func (v valuesFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("want name=value, got %s", s)
+	}
+	v[name] = value
+	return nil
+}
+
+// runCommand runs a snippet with the runner for its language, streaming its
+// output. The placeholders of the snippet are filled in from the flags, or
+// asked for on the terminal, or else get their default values.
+//
+//	snp run [--set name=value] <name> [-- args]
+func runCommand(config Config, snippets []Snippet, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	set := valuesFlag{}
+	fs.Var(set, "set", "set the placeholder `name=value`, may be repeated")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp run [--set name=value] <name> [-- args]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	s, err := resolveSnippet(args[0], snippets)
+	if err != nil {
+		return err
+	}
+	content, err := s.Content(false)
+	if err != nil {
+		return err
+	}
+	values := placeholders(content)
+	ask := isatty.IsTerminal(os.Stdin.Fd())
+	stdin := bufio.NewReader(os.Stdin)
+	for i, p := range values {
+		if v, ok := set[p.name]; ok {
+			values[i].value = v
+			continue
+		}
+		if !ask {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", p.name, p.value)
+		if line, err := stdin.ReadString('\n'); err == nil && strings.TrimSpace(line) != "" {
+			values[i].value = strings.TrimRight(line, "\r\n")
+		}
+	}
+
+	file, cleanup, err := runnableFile(filepath.Join(config.Root, s.Folder, s.File), content, values)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	cmd, err := runnerCommand(context.Background(), config, s.Language, file, args[1:])
+	if err != nil {
+		return err
+	}
+	_ = recordUse(config.StateDir, s)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// checkTargets returns the snippets that are referred to by the names, or
+// all the snippets if there are no names.
+func checkTargets(names []string, snippets []Snippet) ([]Snippet, error) {
+	if len(names) <= 0 {
+		return snippets, nil
+	}
+	var targets []Snippet
+	for _, name := range names {
+		s, err := resolveSnippet(name, snippets)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, s)
+	}
+	return targets, nil
+}
+
+// fmtCommand formats the snippets with the formatters for their languages,
+// printing the snippets that changed and the problems that kept snippets from
+// being formatted.
+//
+//	snp fmt [<name>...]
+func fmtCommand(config Config, snippets []Snippet, args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp fmt [<name>...]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	targets, err := checkTargets(args, snippets)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, s := range targets {
+		changed, diagnostics, err := formatSnippet(config, s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", s, err)
+			failed++
+			continue
+		}
+		for _, d := range diagnostics {
+			fmt.Printf("%s:%s\n", s, d)
+		}
+		if len(diagnostics) > 0 {
+			failed++
+		} else if changed {
+			fmt.Println(s)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s could not be formatted", snippetsString(failed))
+	}
+	return nil
+}
+
+// lintCommand lints the snippets with the linters for their languages,
+// printing their problems.
+//
+//	snp lint [<name>...]
+func lintCommand(config Config, snippets []Snippet, args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp lint [<name>...]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	targets, err := checkTargets(args, snippets)
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	for _, s := range targets {
+		diagnostics, err := lintSnippet(config, s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", s, err)
+			problems++
+			continue
+		}
+		for _, d := range diagnostics {
+			fmt.Printf("%s:%s\n", s, d)
+		}
+		problems += len(diagnostics)
+	}
+	if problems > 0 {
+		return fmt.Errorf("found %s", problemsString(problems))
+	}
+	return nil
+}
+
+// clipCommand captures the clipboard into the clipboard history, lists the
+// history or prints one of its clips. It also clears copies of sensitive
+// snippets from the clipboard once they timed out, which the TUI starts it
+// for.
+//
+//	snp clip [--list | <n> | --clear-after <duration>]
+func clipCommand(config Config, args []string) error {
+	fs := flag.NewFlagSet("clip", flag.ContinueOnError)
+	list := fs.Bool("list", false, "list the clipboard history")
+	clearAfter := fs.Duration("clear-after", 0, "clear the clipboard after the `duration` if it holds a copy of sensitive snippets by then")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp clip [--list | <n> | --clear-after <duration>]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 || (*list && len(args) > 0) || (*clearAfter > 0 && (*list || len(args) > 0)) {
+		fs.Usage()
+		return errUsage
+	}
+
+	if *clearAfter > 0 {
+		cb, err := newClipboard(config.Clipboard, config.StateDir)
+		if err != nil {
+			return err
+		}
+		time.Sleep(*clearAfter)
+		return clearSensitiveClipboard(cb, config.StateDir, *clearAfter)
+	}
+
+	if *list || len(args) > 0 {
+		h, err := readHistory(config.StateDir)
+		if err != nil {
+			return err
+		}
+		if *list {
+			for i, clip := range h {
+				fmt.Printf("%3d %-4s %s\n", i+1, age(clip.Time), clip.Preview(72))
+			}
+			return nil
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(h) {
+			return fmt.Errorf("no such clip: %s", args[0])
+		}
+		fmt.Print(h[n-1].Content)
+		return nil
+	}
+
+	cb, err := newClipboard(config.Clipboard, config.StateDir)
+	if err != nil {
+		return err
+	}
+	h, err := captureClipboard(cb, config.StateDir)
+	if err != nil {
+		return err
+	}
+	if len(h) <= 0 {
+		return fmt.Errorf("the clipboard is empty")
+	}
+	fmt.Println(h[0].Preview(72))
+	return nil
+}
+
+// tagsFlag collects the tags of a flag that may be repeated.
+type tagsFlag []string
+
+func (t *tagsFlag) String() string { return strings.Join(*t, ",") }
+
+func (t *tagsFlag) Set(s string) error {
+	*t = addTags(*t, parseTags(s))
+	return nil
+}
+
+// defaultCommand saves the snippet that is piped in under the name or, when
+// nothing is piped in, prints the snippet that best matches the name.
+//
+//	snp <name>
+//	... | snp [add flags] <name>
+func defaultCommand(config Config, snippets []Snippet, args []string, stdin string) error {
+	if stdin != "" {
+		return addCommand(config, args, stdin)
+	}
+	snippet := findSnippet(args[0], snippets)
+	if snippet.File == "" {
+		return nil
+	}
+	_ = recordUse(config.StateDir, snippet)
+	content, err := snippet.Content(isatty.IsTerminal(os.Stdout.Fd()))
+	if err != nil {
+		return err
+	}
+	fmt.Print(content)
+	return nil
+}
+
+// addCommand saves the snippet in stdin or read from a file and prints the
+// name of the saved snippet. A snippet is only overwritten or appended to
+// when asked for, and is formatted and linted after saving it if configured
+// to.
+//
+//	snp add [--folder <folder>] [--lang <lang>] [--tag <tags>] [--desc <text>]
+//	        [--file <path>] [--append | --force] [--encrypt] [--sensitive] [name]
+func addCommand(config Config, args []string, stdin string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	folder := fs.String("folder", "", "folder of the snippet")
+	lang := fs.String("lang", "", "language of the snippet, detected if not given")
+	var tags tagsFlag
+	fs.Var(&tags, "tag", "comma separated tags, may be repeated")
+	desc := fs.String("desc", "", "description of the snippet")
+	path := fs.String("file", "", "read the snippet from the file instead of stdin")
+	appendTo := fs.Bool("append", false, "append to the snippet if it exists")
+	force := fs.Bool("force", false, "overwrite the snippet if it exists")
+	encrypt := fs.Bool("encrypt", false, "encrypt the snippet with the identity file or a passphrase")
+	sensitive := fs.Bool("sensitive", false, "mark the snippet as sensitive")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp add [--folder <folder>] [--lang <lang>] [--tag <tags>] [--desc <text>]")
+		fmt.Fprintln(fs.Output(), "               [--file <path>] [--append | --force] [--encrypt] [--sensitive] [name]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 1 || (*appendTo && *force) {
+		fs.Usage()
+		return errUsage
+	}
+
+	content := stdin
+	if *path != "" {
+		b, err := os.ReadFile(*path)
+		if err != nil {
+			return err
+		}
+		content = string(b)
+	}
+	if content == "" {
+		return errors.New("nothing to add, pipe the snippet in or use --file")
+	}
+
+	dir, name, language := defaultSnippetFolder, "", ""
+	target := filepath.Base(*path)
+	if len(args) > 0 {
+		target = args[0]
+		if i := strings.LastIndex(target, "/"); i >= 0 {
+			dir, target = target[:i], target[i+1:]
+		}
+	}
+	if strings.HasSuffix(target, encryptedExt) {
+		target = strings.TrimSuffix(target, encryptedExt)
+		*encrypt = true
+	}
+	if target != "." {
+		name = target
+		if i := strings.LastIndex(target, "."); i > 0 {
+			name, language = target[:i], target[i+1:]
+		}
+	}
+	if *folder != "" {
+		dir = *folder
+	}
+	if *lang != "" {
+		language = *lang
+	}
+	if language == "" {
+		language = detectLanguage(content, *path, config.DefaultLanguage)
+	}
+	if err := validFolder(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(config.Root, dir), 0755); err != nil {
+		return err
+	}
+
+	s := Snippet{Encrypted: *encrypt}
+	var file string
+	if name == "" {
+		file = uniqueFile(config.Root, dir, s.fileName(defaultSnippetName, language))
+	} else {
+		file = s.fileName(name, language)
+	}
+	s = newSnippet(dir, file)
+	filePath := filepath.Join(config.Root, dir, file)
+	_, err = os.Stat(filePath)
+	exists := err == nil
+	if exists && !*appendTo && !*force {
+		return fmt.Errorf("%w: %s/%s, use --force to overwrite or --append to add to it", errSnippetExists, dir, file)
+	}
+
+	var k *keyring
+	if s.Encrypted {
+		if k, err = cliKeyring(config, !exists); err != nil {
+			return err
+		}
+	}
+	switch {
+	case exists && *appendTo && s.Encrypted:
+		var existing string
+		if existing, err = readSnippetFile(config, s, k); err == nil {
+			err = writeSnippetFile(config, s, k, existing+content)
+		}
+	case exists && *appendTo:
+		err = appendFile(filePath, content)
+	default:
+		err = writeSnippetFile(config, s, k, content)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(tags) > 0 || *desc != "" || *sensitive {
+		err := updateLibrary(config.Root, func(lib Library) {
+			key := metadataKey(dir, file)
+			md := lib[key]
+			md.Tags = addTags(md.Tags, tags)
+			if *desc != "" {
+				md.Description = *desc
+			}
+			md.Sensitive = md.Sensitive || *sensitive
+			lib[key] = md
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// The snippet is saved even if it has problems, which are reported
+	// along with it.
+	if config.Check.OnSave {
+		diagnostics, err := checkSnippet(config, s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", s, err)
+		}
+		for _, d := range diagnostics {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", s, d)
+		}
+	}
+	fmt.Println(s)
+	return nil
+}
+
+// serveCommand serves the JSON API over the snippets on the address, or on
+// the Unix socket, until it is interrupted.
+//
+//	snp serve [--addr <host:port> | --socket <path>]
+func serveCommand(config Config, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", defaultAddr, "serve on the `host:port`")
+	socket := fs.String("socket", "", "serve on the Unix socket at the `path` instead")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp serve [--addr <host:port> | --socket <path>]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	network, address := "tcp", *addr
+	if *socket != "" {
+		network, address = "unix", *socket
+		// A socket left behind by a server that did not shut down cleanly
+		// would keep the new one from listening.
+		if fi, err := os.Lstat(address); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(address)
+		}
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{Handler: newAPIServer(config), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Shutdown(context.Background())
+	}()
+	fmt.Fprintf(os.Stderr, "serving snippets on %s\n", l.Addr())
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// lspCommand speaks the Language Server Protocol on stdin and stdout,
+// completing the snippets of the language of the documents. The snippets are
+// reloaded whenever the snippet root changes.
+//
+//	snp lsp [--stdio]
+func lspCommand(config Config, args []string) error {
+	fs := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	// Clients pass --stdio to select the only transport there is.
+	fs.Bool("stdio", true, "speak the protocol on stdin and stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp lsp [--stdio]")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	l := newLSPServer(config, os.Stdin, os.Stdout)
+	if w, err := newWatcher(config.Root); err == nil {
+		defer w.Close()
+		go l.watch(w)
+	}
+	return l.serve()
+}
+
+// exportCommand exports the snippets as a static site that can be browsed
+// and searched, into an empty directory or one that holds a previous export.
+// Encrypted snippets are left out unless asked for, in which case they are
+// decrypted into the site.
+//
+//	snp export html [--include-encrypted] <dir>
+func exportCommand(config Config, snippets []Snippet, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	encrypted := fs.Bool("include-encrypted", false, "decrypt encrypted snippets and include them in the site")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp export html [--include-encrypted] <dir>")
+		fs.PrintDefaults()
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 || args[0] != "html" {
+		fs.Usage()
+		return errUsage
+	}
+
+	var k *keyring
+	if *encrypted {
+		if k, err = cliKeyring(config, false); err != nil {
+			return err
+		}
+	}
+	n, err := exportSite(config, snippets, args[1], k)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("exported %d snippets to %s\n", n, args[1])
+	if skipped := len(snippets) - n; skipped > 0 {
+		fmt.Fprintf(os.Stderr, "left out %d encrypted or sensitive snippets\n", skipped)
+	}
+	return nil
+}
+
+// themesCommand lists the themes or previews a theme.
+//
+//	snp themes list
+//	snp themes preview [name]
+func themesCommand(config Config, args []string) error {
+	fs := flag.NewFlagSet("themes", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: snp themes list")
+		fmt.Fprintln(fs.Output(), "       snp themes preview [name]")
+	}
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		for _, name := range listThemes() {
+			current := " "
+			if name == config.Theme {
+				current = "*"
+			}
+			fmt.Println(current, name)
+		}
+		return nil
+	case len(args) >= 1 && len(args) <= 2 && args[0] == "preview":
+		if len(args) == 2 {
+			t, err := loadTheme(args[1])
+			if err != nil {
+				return err
+			}
+			config.Theme, config.theme = t.Name, t
+		}
+		fmt.Print(themePreview(config))
+		return nil
+	}
+	fs.Usage()
+	return errUsage
+}
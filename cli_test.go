@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMoveTarget(t *testing.T) {
+	hello := newSnippet("Code", "Hello.go")
+	secret := newSnippet("Code", "Secret.sh.age")
+	tests := []struct {
+		to           string
+		s            Snippet
+		folder, file string
+	}{
+		{"Notes/", hello, "Notes", "Hello.go"},
+		{"Notes/Bye", hello, "Notes", "Bye.go"},
+		{"Notes/Bye.sh", hello, "Notes", "Bye.sh"},
+		{"Bye.sh", hello, "Code", "Bye.sh"},
+		{"Bye", hello, "Code", "Bye.go"},
+		{"Notes/", secret, "Notes", "Secret.sh.age"},
+		{"Token", secret, "Code", "Token.sh.age"},
+		{"Token.py", secret, "Code", "Token.py.age"},
+		{"Token.py.age", secret, "Code", "Token.py.age"},
+	}
+	for _, tt := range tests {
+		folder, file := moveTarget(tt.to, tt.s)
+		if folder != tt.folder || file != tt.file {
+			t.Errorf("moveTarget(%q, %s) = (%s, %s), want (%s, %s)", tt.to, tt.s.File, folder, file, tt.folder, tt.file)
+		}
+	}
+}
+
+func TestResolveSnippet(t *testing.T) {
+	snippets := []Snippet{
+		newSnippet("misc", "hello.go"),
+		newSnippet("misc", "hello.sh"),
+		newSnippet("shell", "list.sh"),
+		newSnippet("notes", "list.sh"),
+		newSnippet("misc", "token.sh.age"),
+	}
+	tests := []struct {
+		name string
+		want string
+		err  bool
+	}{
+		{"misc/hello.go", "misc/hello.go", false},
+		{"hello.sh", "misc/hello.sh", false},
+		{"shell/list", "shell/list.sh", false},
+		{"misc/token.sh", "misc/token.sh", false},
+		{"misc/token.sh.age", "misc/token.sh", false},
+		{"misc/hello", "", true},
+		{"list.sh", "", true},
+		{"nope", "", true},
+	}
+	for _, tt := range tests {
+		s, err := resolveSnippet(tt.name, snippets)
+		if (err != nil) != tt.err {
+			t.Errorf("resolveSnippet(%q) error = %v, want error %t", tt.name, err, tt.err)
+			continue
+		}
+		if err == nil && s.String() != tt.want {
+			t.Errorf("resolveSnippet(%q) = %s, want %s", tt.name, s, tt.want)
+		}
+	}
+}
+
+func TestMoveCommand(t *testing.T) {
+	tests := []struct {
+		to   string
+		want string
+	}{
+		{"notes", "notes/list.sh"},
+		{"listing", "shell/listing.sh"},
+		{"misc/listing", "misc/listing.sh"},
+	}
+	for _, tt := range tests {
+		config, snippets := testSnippets(t, nil)
+		_, err := captureStdout(t, func() error { return moveCommand(config, snippets, []string{"shell/list.sh", tt.to}) })
+		if err != nil {
+			t.Errorf("snp mv shell/list.sh %s: %v", tt.to, err)
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(config.Root, filepath.FromSlash(tt.want))); err != nil {
+			t.Errorf("snp mv shell/list.sh %s: %v", tt.to, err)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		force bool
+		to    string
+		rest  []string
+	}{
+		{[]string{"a", "b"}, false, "", []string{"a", "b"}},
+		{[]string{"--force", "a", "b"}, true, "", []string{"a", "b"}},
+		{[]string{"a", "--force", "b", "--to", "x"}, true, "x", []string{"a", "b"}},
+		{[]string{"a", "--", "--force", "b"}, false, "", []string{"a", "--force", "b"}},
+		{nil, false, "", nil},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		force := fs.Bool("force", false, "")
+		to := fs.String("to", "", "")
+		rest, err := parseArgs(fs, tt.args)
+		if err != nil {
+			t.Errorf("parseArgs(%q): %v", tt.args, err)
+			continue
+		}
+		if *force != tt.force || *to != tt.to || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("parseArgs(%q) = %q with force %t and to %q, want %q with force %t and to %q", tt.args, rest, *force, *to, tt.rest, tt.force, tt.to)
+		}
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err := parseArgs(fs, []string{"a", "--nope"}); err == nil {
+		t.Error("parseArgs accepted an unknown flag")
+	}
+}
+
+func TestLibraryMove(t *testing.T) {
+	lib := Library{
+		"misc/a.sh": {Tags: []string{"a"}},
+		"misc/b.sh": {Tags: []string{"b"}},
+		"misc/c.sh": {Description: "c"},
+	}
+	lib.move("misc", "a.sh", "shell", "a.sh")
+	// Overwriting a snippet with one without metadata drops its metadata.
+	lib.move("misc", "d.sh", "misc", "b.sh")
+	want := Library{
+		"shell/a.sh": {Tags: []string{"a"}},
+		"misc/c.sh":  {Description: "c"},
+	}
+	if !reflect.DeepEqual(lib, want) {
+		t.Errorf("got %v, want %v", lib, want)
+	}
+}
+
+func TestLibraryWrite(t *testing.T) {
+	root := t.TempDir()
+	lib := Library{"misc/a.sh": {Tags: []string{"a"}}}
+	for i := 0; i < 2; i++ {
+		if err := lib.write(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := readLibrary(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, lib) {
+		t.Errorf("got %v, want %v", got, lib)
+	}
+	// Only the metadata file is left behind.
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != metadataFile {
+		t.Errorf("got %d entries in the root, want only %s", len(entries), metadataFile)
+	}
+}
+
+// captureStdout returns what f prints to stdout along with its error.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	err = f()
+	w.Close()
+	return <-out, err
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		content, filename string
+		want              string
+	}{
+		{"package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(1) }\n", "", "go"},
+		{"#!/bin/sh\nls\n", "", "sh"},
+		{"x = 1\n", "script.py", "py"},
+		{"x = 1\n", "/tmp/notes.md", "md"},
+		{"", "Makefile", "mak"},
+		{"just some words\n", "", "txt"},
+	}
+	for _, tt := range tests {
+		if got := detectLanguage(tt.content, tt.filename, "txt"); got != tt.want {
+			t.Errorf("detectLanguage(%q, %q) = %s, want %s", tt.content, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestAddCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		// file is the snippet that should be written, relative to the root.
+		file    string
+		content string
+		err     error
+	}{
+		{"named", []string{"shell/up.sh"}, "uptime\n", "shell/up.sh", "uptime\n", nil},
+		{"detected language", []string{"--folder", "code", "main"}, "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(1) }\n", "code/main.go", "", nil},
+		{"unnamed", []string{"--lang", "sh"}, "ls\n", "misc/Untitled.sh", "ls\n", nil},
+		{"append", []string{"--append", "shell/list.sh"}, "pwd\n", "shell/list.sh", "ls -la\npwd\n", nil},
+		{"force", []string{"--force", "shell/list.sh"}, "pwd\n", "shell/list.sh", "pwd\n", nil},
+		{"exists", []string{"shell/list.sh"}, "pwd\n", "shell/list.sh", "ls -la\n", errSnippetExists},
+		{"empty", []string{"shell/new.sh"}, "", "", "", errors.New("nothing to add")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _ := testSnippets(t, nil)
+			_, err := captureStdout(t, func() error { return addCommand(config, tt.args, tt.stdin) })
+			if (err != nil) != (tt.err != nil) || (tt.err == errSnippetExists && !errors.Is(err, errSnippetExists)) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if tt.file == "" {
+				return
+			}
+			b, err := os.ReadFile(filepath.Join(config.Root, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.content != "" && string(b) != tt.content {
+				t.Errorf("got %q, want %q", b, tt.content)
+			}
+		})
+	}
+}
+
+func TestDefaultCommand(t *testing.T) {
+	config, snippets := testSnippets(t, nil)
+	// The snippet that is looked up reads the config from the environment.
+	t.Setenv("SNP_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	t.Setenv("SNP_ROOT", config.Root)
+
+	out, err := captureStdout(t, func() error { return defaultCommand(config, snippets, []string{"list"}, "") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "ls -la\n" {
+		t.Errorf("looking up the snippet printed %q", out)
+	}
+
+	out, err = captureStdout(t, func() error { return defaultCommand(config, snippets, []string{"shell/up.sh"}, "uptime\n") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "shell/up.sh\n" {
+		t.Errorf("adding the snippet printed %q", out)
+	}
+	if b, err := os.ReadFile(filepath.Join(config.Root, "shell", "up.sh")); err != nil || string(b) != "uptime\n" {
+		t.Errorf("got %q, %v", b, err)
+	}
+}
+
+func TestAddCommandCheck(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt is not installed")
+	}
+	const unformatted = "package main\nfunc main() {  }\n"
+	tests := []struct {
+		name       string
+		formatters map[string]string
+		want       string
+	}{
+		{"default", nil, "package main\n\nfunc main() {}\n"},
+		// An empty command turns the formatter off.
+		{"disabled", map[string]string{"go": ""}, unformatted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _ := testSnippets(t, nil)
+			config.Check.OnSave = true
+			config.Check.Formatters = tt.formatters
+			_, err := captureStdout(t, func() error { return addCommand(config, []string{"code/main.go"}, unformatted) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(filepath.Join(config.Root, "code", "main.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("got %q, want %q", b, tt.want)
+			}
+		})
+	}
+}
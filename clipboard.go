@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/slices"
+)
+
+// Clipboard backends that can be selected in the config.
+const (
+	autoClipboard   = "auto"
+	nativeClipboard = "native"
+	osc52Clipboard  = "osc52"
+	tmuxClipboard   = "tmux"
+	fileClipboard   = "file"
+)
+
+var errUnknownClipboard = errors.New("unknown clipboard")
+
+// Clipboard reads and writes a clipboard.
+type Clipboard interface {
+	// Name returns the name of the backend.
+	Name() string
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// newClipboard returns the clipboard backend with the name, detecting the
+// backend that fits the session best for "auto" or an empty name.
+//
+// Inside tmux the tmux buffers are used, over SSH the terminal clipboard is
+// used through OSC 52, and otherwise the native clipboard tools are used when
+// they are available. The file clipboard is the last resort.
+func newClipboard(name, stateDir string) (Clipboard, error) {
+	if name == "" || name == autoClipboard {
+		name = detectClipboard()
+	}
+	switch name {
+	case nativeClipboard:
+		return systemClipboard{}, nil
+	case osc52Clipboard:
+		return terminalClipboard{os.Stderr, localClipboard{clipboardFile(stateDir)}}, nil
+	case tmuxClipboard:
+		return tmuxBuffer{}, nil
+	case fileClipboard:
+		return localClipboard{clipboardFile(stateDir)}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", errUnknownClipboard, name)
+}
+
+// detectClipboard returns the name of the clipboard backend for the session.
+func detectClipboard() string {
+	switch {
+	case os.Getenv("TMUX") != "":
+		return tmuxClipboard
+	case os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "":
+		return osc52Clipboard
+	case !clipboard.Unsupported:
+		return nativeClipboard
+	}
+	return fileClipboard
+}
+
+// clipboardFile returns the path of the file used by the file clipboard.
+func clipboardFile(stateDir string) string {
+	return filepath.Join(stateDir, "clipboard")
+}
+
+// systemClipboard uses the clipboard tools of the system, such as pbcopy,
+// xclip or wl-copy.
+type systemClipboard struct{}
+
+func (systemClipboard) Name() string               { return nativeClipboard }
+func (systemClipboard) ReadAll() (string, error)   { return clipboard.ReadAll() }
+func (systemClipboard) WriteAll(text string) error { return clipboard.WriteAll(text) }
+
+// terminalClipboard copies to the clipboard of the terminal with the OSC 52
+// escape sequence, which also works over SSH. Terminals do not allow reading
+// their clipboard, so copies are also kept in a file to paste from. Copies of
+// sensitive snippets are not, only the hash that they are cleared by.
+type terminalClipboard struct {
+	out  io.Writer
+	file localClipboard
+}
+
+func (terminalClipboard) Name() string { return osc52Clipboard }
+
+func (c terminalClipboard) ReadAll() (string, error) { return c.file.ReadAll() }
+
+func (c terminalClipboard) WriteAll(text string) error {
+	osc52.NewOutput(c.out, os.Environ()).Copy(text)
+	if err := removeFile(c.hashFile()); err != nil {
+		return err
+	}
+	return c.file.WriteAll(text)
+}
+
+func (c terminalClipboard) WriteSensitive(text string) error {
+	osc52.NewOutput(c.out, os.Environ()).Copy(text)
+	if err := c.file.WriteAll(""); err != nil {
+		return err
+	}
+	return os.WriteFile(c.hashFile(), []byte(contentHash(text)), 0600)
+}
+
+// SensitiveHash returns the hash of the copy of sensitive snippets, or
+// nothing if something else was copied since.
+func (c terminalClipboard) SensitiveHash() (string, error) {
+	hash, err := os.ReadFile(c.hashFile())
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return string(hash), err
+}
+
+func (c terminalClipboard) ClearSensitive() error {
+	osc52.NewOutput(c.out, os.Environ()).Copy("")
+	return removeFile(c.hashFile())
+}
+
+// hashFile returns the path of the hash of the copy of sensitive snippets.
+func (c terminalClipboard) hashFile() string {
+	return c.file.path + ".sensitive"
+}
+
+// tmuxBuffer uses the paste buffers of tmux, which are passed on to the
+// clipboard of the terminal when tmux is configured to do so. Sensitive
+// snippets are copied to a buffer of their own, which is deleted to clear
+// them.
+type tmuxBuffer struct{}
+
+// tmuxSensitiveBuffer is the name of the tmux buffer that sensitive snippets
+// are copied to.
+const tmuxSensitiveBuffer = "snp-sensitive"
+
+func (tmuxBuffer) Name() string { return tmuxClipboard }
+
+func (tmuxBuffer) ReadAll() (string, error) {
+	out, err := exec.Command("tmux", "save-buffer", "-").Output()
+	if err != nil {
+		return "", fmt.Errorf("tmux: %w", err)
+	}
+	return string(out), nil
+}
+
+// WriteAll loads the text into a new tmux buffer and asks tmux to pass it on
+// to the terminal, which older versions of tmux do not support.
+func (b tmuxBuffer) WriteAll(text string) error {
+	return b.load(text)
+}
+
+func (b tmuxBuffer) WriteSensitive(text string) error {
+	return b.load(text, "-b", tmuxSensitiveBuffer)
+}
+
+// SensitiveHash returns the hash of the copy of sensitive snippets, or
+// nothing if it was cleared.
+func (tmuxBuffer) SensitiveHash() (string, error) {
+	out, err := exec.Command("tmux", "save-buffer", "-b", tmuxSensitiveBuffer, "-").Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("tmux: %w", err)
+	}
+	return contentHash(string(out)), nil
+}
+
+func (tmuxBuffer) ClearSensitive() error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("tmux", "delete-buffer", "-b", tmuxSensitiveBuffer)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("tmux: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// load loads the text into a tmux buffer with the options, passing it on to
+// the terminal if tmux supports it.
+func (tmuxBuffer) load(text string, options ...string) error {
+	var err error
+	for _, args := range [][]string{{"load-buffer", "-w"}, {"load-buffer"}} {
+		cmd := exec.Command("tmux", append(append(args, options...), "-")...)
+		cmd.Stdin = strings.NewReader(text)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err = cmd.Run(); err == nil {
+			return nil
+		}
+		err = fmt.Errorf("tmux: %s", strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// localClipboard keeps the clipboard in a file, which works everywhere but
+// only within snp.
+type localClipboard struct{ path string }
+
+func (localClipboard) Name() string { return fileClipboard }
+
+func (c localClipboard) ReadAll() (string, error) {
+	content, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return string(content), err
+}
+
+// WriteAll writes the text to the file, or removes the file when the text is
+// empty so that cleared copies of sensitive snippets leave nothing behind.
+func (c localClipboard) WriteAll(text string) error {
+	if text == "" {
+		return removeFile(c.path)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, []byte(text), 0600)
+}
+
+// removeFile removes the file if it exists.
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// copySnippets copies the contents of the target snippets to the clipboard.
+// The contents of sensitive snippets are kept out of the history and cleared
+// from the clipboard later, by a snp process of its own if it can be started
+// or else by the application.
+//
+// The contents are read before returning the Cmd, which must not touch the
+// model as it runs alongside Update.
+func (m *Model) copySnippets() tea.Cmd {
+	targets := m.targets()
+	sensitive := slices.IndexFunc(targets, m.config.isSensitive) >= 0
+	content, err := m.targetsContent()
+	if err != nil {
+		return func() tea.Msg { return errorMsg{err} }
+	}
+	cb, stateDir, clearAfter := m.clipboard, m.config.StateDir, m.config.Sensitive.clearAfter()
+	return func() tea.Msg {
+		var err error
+		if sensitive {
+			err = copySensitive(cb, stateDir, content)
+		} else {
+			err = cb.WriteAll(content)
+		}
+		if err != nil {
+			return errorMsg{fmt.Errorf("copy to %s clipboard: %w", cb.Name(), err)}
+		}
+		_ = recordUse(stateDir, targets...)
+		if sensitive {
+			if err := startClipboardClearer(clearAfter); err != nil {
+				return sensitiveCopiedMsg{}
+			}
+			return changeStateMsg{copyingState}
+		}
+		_, _ = recordClip(stateDir, content)
+		return changeStateMsg{copyingState}
+	}
+}
+
+// pasteClipboard appends the contents of the clipboard to the selected
+// snippet.
+func (m *Model) pasteClipboard() error {
+	content, err := m.clipboard.ReadAll()
+	if err != nil {
+		return fmt.Errorf("paste from %s clipboard: %w", m.clipboard.Name(), err)
+	}
+	if !isSensitiveCopy(m.config.StateDir, content) {
+		_, _ = recordClip(m.config.StateDir, content)
+	}
+	return m.appendSnippet(m.selectedSnippet(), content)
+}
+
+// appendFile appends the content to the file, creating it if needed.
+func appendFile(path, content string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(content)
+	return err
+}
@@ -2,8 +2,10 @@ package main
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/adrg/xdg"
+	"github.com/muesli/termenv"
 )
 
 // TODO:
@@ -16,8 +18,47 @@ type Config struct {
 
 	DefaultLanguage string `env:"SNP_DEFAULT_LANGUAGE" yaml:"default_language"`
 
+	// Theme is the name of the UI theme, or of the chroma style to highlight
+	// snippets with for configs that predate themes.
 	Theme string `env:"SNP_THEME" yaml:"theme"`
 
+	// LightTheme is the theme that is used instead of Theme when the terminal
+	// has a light background. Without it, the default theme gives way to
+	// default-light, while other themes are kept.
+	LightTheme string `env:"SNP_LIGHT_THEME" yaml:"light_theme"`
+
+	// Appearance forces the dark or light theme, or detects the background
+	// of the terminal when set to auto.
+	Appearance string `env:"SNP_APPEARANCE" yaml:"appearance"`
+
+	// theme is the UI theme that was loaded for Theme.
+	theme Theme
+
+	// profile is the color profile of the terminal.
+	profile termenv.Profile
+
+	Editor EditorConfig `yaml:"editor"`
+
+	Preview PreviewConfig `yaml:"preview"`
+
+	// Runners are the command templates that snippets are run with by
+	// language, e.g. py: python3 {file}. {file} is replaced by the path of
+	// the snippet file and {args} by the arguments, which are appended when
+	// not placed. They override the runners of well-known languages.
+	Runners map[string]string `yaml:"runners"`
+
+	Check CheckConfig `yaml:"check"`
+
+	Secrets SecretsConfig `yaml:"secrets"`
+
+	Sensitive SensitiveConfig `yaml:"sensitive"`
+
+	// Clipboard is the clipboard backend: auto, native, osc52, tmux or file.
+	Clipboard string `env:"SNP_CLIPBOARD" yaml:"clipboard"`
+
+	// StateDir is where the clipboard history and other state is kept.
+	StateDir string `env:"SNP_STATE_DIR" yaml:"state_dir"`
+
 	ForegroundColor    string `env:"SNP_FOREGROUND" yaml:"foreground"`
 	BackgroundColor    string `env:"SNP_BACKGROUND" yaml:"background"`
 	RedColor           string `env:"SNP_RED" yaml:"red"`
@@ -35,27 +76,140 @@ type Config struct {
 	GrayColor    string `env:"SNP_GRAY" yaml:"gray"`
 }
 
+// EditorConfig holds the options for editing snippets.
+//
+// Command is a command template in which {path} is replaced by the path of
+// the snippet file and {line} by the line to jump to, e.g.
+//
+//	editor:
+//	  command: nvim +{line} {path}
+//	  languages:
+//	    md: code --wait --goto {path}:{line}
+//
+// When no command is configured, $VISUAL or $EDITOR is used.
+type EditorConfig struct {
+	Command string `env:"SNP_EDITOR" yaml:"command"`
+
+	// Languages overrides the command for snippets of a language.
+	Languages map[string]string `yaml:"languages"`
+
+	// GUI runs the editor without suspending the application, which is
+	// detected for well-known graphical editors.
+	GUI bool `env:"SNP_EDITOR_GUI" yaml:"gui"`
+
+	// Inline edits snippets in the content pane.
+	Inline bool `env:"SNP_INLINE_EDITOR" yaml:"inline"`
+}
+
+// PreviewConfig holds the options for previewing snippets in the content
+// pane. Wrapping and whitespace can also be toggled while previewing.
+type PreviewConfig struct {
+	// TabWidth is the number of columns that tabs are expanded to.
+	TabWidth int `env:"SNP_TAB_WIDTH" yaml:"tab_width"`
+
+	// Wrap soft wraps lines that are wider than the content pane instead
+	// of scrolling them horizontally.
+	Wrap bool `env:"SNP_WRAP" yaml:"wrap"`
+
+	// Whitespace shows spaces and tabs.
+	Whitespace bool `env:"SNP_SHOW_WHITESPACE" yaml:"whitespace"`
+
+	// Markdown renders Markdown snippets as formatted documents rather than
+	// highlighting them as code.
+	Markdown bool `env:"SNP_RENDER_MARKDOWN" yaml:"markdown"`
+
+	// Notes are folders whose snippets are Markdown documents whatever their
+	// language.
+	Notes []string `env:"SNP_NOTES" yaml:"notes"`
+}
+
+// tabWidth returns the configured tab width, or the default for widths that
+// are not positive.
+func (c PreviewConfig) tabWidth() int {
+	if c.TabWidth <= 0 {
+		return defaultTabWidth
+	}
+	return c.TabWidth
+}
+
+// CheckConfig holds the formatters and linters that snippets are checked
+// with. Their command templates are set by language, in which {file} is
+// replaced by the path of the snippet file, e.g.
+//
+//	check:
+//	  formatters:
+//	    go: gofmt -w {file}
+//	  linters:
+//	    sh: shellcheck -f gcc {file}
+//
+// They override the tools of well-known languages, which are only used when
+// installed, and an empty command turns the tool of a language off. Problems
+// are read from their output as file:line:column: message.
+type CheckConfig struct {
+	// OnSave formats and lints snippets after they are edited. It is off by
+	// default, as formatters rewrite the snippets in place; snp fmt and
+	// snp lint check them when asked to.
+	OnSave bool `env:"SNP_CHECK_ON_SAVE" yaml:"on_save"`
+
+	// Formatters format snippets in place.
+	Formatters map[string]string `yaml:"formatters"`
+
+	// Linters report the problems of snippets.
+	Linters map[string]string `yaml:"linters"`
+}
+
+// SecretsConfig holds the options for encrypted snippets. They are encrypted
+// with age to the identity file, or else with a passphrase that is asked for
+// when unlocking them.
+//
+// External editors and runners work on a decrypted copy in $XDG_RUNTIME_DIR
+// or /dev/shm, falling back to the temporary directory, which is removed
+// afterwards. The inline editor keeps the contents in memory only.
+type SecretsConfig struct {
+	// Identity is the path of an age identity file, as written by
+	// age-keygen.
+	Identity string `env:"SNP_IDENTITY" yaml:"identity"`
+}
+
+// SensitiveConfig holds the options for sensitive snippets, such as tokens
+// and passwords. They are masked in the content pane, kept out of the
+// clipboard history and cleared from the clipboard after copying them.
+//
+// Snippets with one of the tags or in one of the folders are sensitive, as
+// are snippets with sensitive: true in their metadata.
+type SensitiveConfig struct {
+	Tags []string `env:"SNP_SENSITIVE_TAGS" yaml:"tags"`
+
+	Folders []string `env:"SNP_SENSITIVE_FOLDERS" yaml:"folders"`
+
+	// ClearAfter is how long a sensitive snippet stays on the clipboard
+	// after copying it, unless something else was copied since. It is
+	// cleared by a snp clip process of its own, so that it can still be
+	// pasted after quitting snp.
+	ClearAfter time.Duration `env:"SNP_CLEAR_AFTER" yaml:"clear_after"`
+}
+
+// clearAfter returns how long sensitive snippets stay on the clipboard.
+func (c SensitiveConfig) clearAfter() time.Duration {
+	if c.ClearAfter <= 0 {
+		return defaultClearAfter
+	}
+	return c.ClearAfter
+}
+
 func newConfig() Config {
+	theme, _ := loadTheme(defaultTheme)
 	return Config{
 		Root: defaultRoot(),
 		// File:                "snippets.json",
-		DefaultLanguage:    defaultLanguage,
-		Theme:              "dracula",
-		ForegroundColor:    "15",
-		BackgroundColor:    "0",
-		RedColor:           "1",
-		GreenColor:         "2",
-		YellowColor:        "3",
-		BlueColor:          "4",
-		MagentaColor:       "5",
-		CyanColor:          "6",
-		BrightRedColor:     "9",
-		BrightGreenColor:   "10",
-		BrightYellowColor:  "11",
-		BrightBlueColor:    "12",
-		BrightMagentaColor: "13",
-		BrightCyanColor:    "14",
-		GrayColor:          "7",
+		DefaultLanguage: defaultLanguage,
+		Theme:           defaultTheme,
+		theme:           theme,
+		Appearance:      autoAppearance,
+		Clipboard:       autoClipboard,
+		StateDir:        defaultStateDir(),
+		Preview:         PreviewConfig{TabWidth: defaultTabWidth, Markdown: true},
+		Sensitive:       SensitiveConfig{Tags: []string{"sensitive"}, ClearAfter: defaultClearAfter},
 	}
 }
 
@@ -63,3 +217,7 @@ func newConfig() Config {
 // default helpers for the configuration.
 // We use $XDG_DATA_HOME to avoid cluttering the user's home directory.
 func defaultRoot() string { return filepath.Join(xdg.DataHome, "snp") }
+
+// defaultStateDir returns the directory in $XDG_STATE_HOME for state that is
+// not worth keeping with the snippets.
+func defaultStateDir() string { return filepath.Join(xdg.StateHome, "snp") }
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// detach leaves the command as it is, as processes outlive the process that
+// started them on this system.
+func detach(cmd *exec.Cmd) {}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/slices"
+)
+
+// lineArguments are the arguments that well-known editors need to open a file
+// at a line.
+var lineArguments = map[string]string{
+	"vi":          "+{line} {path}",
+	"vim":         "+{line} {path}",
+	"nvim":        "+{line} {path}",
+	"gvim":        "-f +{line} {path}",
+	"nano":        "+{line} {path}",
+	"micro":       "+{line} {path}",
+	"emacs":       "+{line} {path}",
+	"emacsclient": "+{line} {path}",
+	"kak":         "+{line} {path}",
+	"hx":          "{path}:{line}",
+	"code":        "--wait --goto {path}:{line}",
+	"codium":      "--wait --goto {path}:{line}",
+	"subl":        "--wait {path}:{line}",
+	"zed":         "--wait {path}:{line}",
+}
+
+// guiEditors are the editors that open their own window.
+var guiEditors = []string{"code", "codium", "subl", "zed", "gvim", "mvim", "gedit", "kate", "mate"}
+
+// environmentEditor returns the editor set in $VISUAL or $EDITOR.
+func environmentEditor() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+	return os.Getenv("EDITOR")
+}
+
+// editorTemplate returns the command template to edit snippets of the
+// language with.
+//
+// An editor from the environment without placeholders gets the arguments to
+// jump to a line if it is a well-known editor.
+func editorTemplate(config EditorConfig, language string) string {
+	if t := strings.TrimSpace(config.Languages[language]); t != "" {
+		return t
+	}
+	if t := strings.TrimSpace(config.Command); t != "" {
+		return t
+	}
+	editor := strings.TrimSpace(environmentEditor())
+	if editor == "" {
+		editor = "vim"
+	}
+	if strings.Contains(editor, "{path}") {
+		return editor
+	}
+	name := filepath.Base(strings.Fields(editor)[0])
+	if args, ok := lineArguments[name]; ok {
+		if strings.Contains(editor, "--wait") {
+			args = strings.TrimPrefix(args, "--wait ")
+		}
+		return editor + " " + args
+	}
+	return editor + " {path}"
+}
+
+// splitCommand splits the command into its arguments, keeping quoted
+// arguments together.
+func splitCommand(command string) []string {
+	var (
+		args    []string
+		arg     strings.Builder
+		quote   rune
+		pending bool
+	)
+	for _, r := range command {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			pending = true
+		case r == ' ' || r == '\t':
+			if pending {
+				args = append(args, arg.String())
+				arg.Reset()
+				pending = false
+			}
+		default:
+			arg.WriteRune(r)
+			pending = true
+		}
+	}
+	if pending {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// editorCommand returns the command that opens the file at the line in the
+// editor for the language, and whether the editor opens its own window.
+func editorCommand(config EditorConfig, language, path string, line int) (*exec.Cmd, bool) {
+	args := splitCommand(editorTemplate(config, language))
+	if len(args) <= 0 {
+		args = []string{"vim", "{path}"}
+	}
+	hasPath := false
+	for i, arg := range args {
+		hasPath = hasPath || strings.Contains(arg, "{path}")
+		arg = strings.ReplaceAll(arg, "{path}", path)
+		args[i] = strings.ReplaceAll(arg, "{line}", strconv.Itoa(line))
+	}
+	if !hasPath {
+		args = append(args, path)
+	}
+	gui := config.GUI || slices.Contains(guiEditors, filepath.Base(args[0]))
+	return exec.Command(args[0], args[1:]...), gui
+}
+
+// editLine returns the line that the editor should jump to, which is the
+// first line containing the search term, the current match of the search in
+// the content pane or else the first line that is visible in the content pane.
+func (m *Model) editLine() int {
+	term := strings.ToLower(m.List().FilterValue())
+	if term != "" {
+		content, err := m.readSnippet(m.selectedSnippet())
+		if err == nil {
+			for i, line := range strings.Split(content, "\n") {
+				if strings.Contains(strings.ToLower(line), term) {
+					return i + 1
+				}
+			}
+		}
+	}
+	if m.searchTerm != "" && m.matchIndex < len(m.matches) {
+		return m.lineAt(m.matches[m.matchIndex]) + 1
+	}
+	return m.lineAt(m.Code.YOffset) + 1
+}
+
+// newEditor returns the text area used to edit snippets in the content pane.
+func newEditor() textarea.Model {
+	ta := textarea.New()
+	ta.CharLimit = 0
+	ta.Prompt = ""
+	ta.ShowLineNumbers = true
+	if !blinkCursors {
+		ta.Cursor.SetCursorMode(cursor.CursorStatic)
+	}
+	return ta
+}
+
+// useInlineEditor reports whether snippets should be edited in the content
+// pane rather than in an external editor, which is the case when configured
+// or when there is no editor to launch.
+func (m *Model) useInlineEditor() bool {
+	if m.config.Editor.Inline {
+		return true
+	}
+	if m.config.Editor.Command != "" || m.config.Editor.Languages[m.selectedSnippet().Language] != "" || environmentEditor() != "" {
+		return false
+	}
+	_, err := exec.LookPath("vim")
+	return err != nil
+}
+
+// startInlineEdit opens the selected snippet in the inline editor.
+func (m *Model) startInlineEdit() tea.Cmd {
+	content, err := m.readSnippet(m.selectedSnippet())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		m.displayError(err.Error())
+		return nil
+	}
+	_ = recordUse(m.config.StateDir, m.selectedSnippet())
+	m.editorOriginal = content
+	m.editorPreview = false
+	m.editor.SetValue(m.editorOriginal)
+	m.state = inlineEditingState
+	m.pane = contentPane
+	m.updateKeyMap()
+	cmd := m.editor.Focus()
+	m.editor, _ = m.editor.Update(tea.KeyMsg{Type: tea.KeyCtrlHome})
+	return cmd
+}
+
+// editorDirty reports whether the inline editor has unsaved changes.
+func (m *Model) editorDirty() bool {
+	return m.state == inlineEditingState && m.editor.Value() != m.editorOriginal
+}
+
+// saveInlineEdit writes the contents of the inline editor to the snippet file
+// and closes the editor.
+func (m *Model) saveInlineEdit() tea.Cmd {
+	if err := m.writeSnippet(m.selectedSnippet(), m.editor.Value()); err != nil {
+		m.displayError(err.Error())
+		return nil
+	}
+	m.stopInlineEdit()
+	return tea.Batch(m.updateContent(), m.checkEditedSnippet(m.selectedSnippet()))
+}
+
+// stopInlineEdit closes the inline editor, discarding unsaved changes.
+func (m *Model) stopInlineEdit() {
+	m.editor.Blur()
+	m.editor.Reset()
+	m.editorPreview = false
+	m.editorDiscarding = false
+	m.state = navigatingState
+	m.updateKeyMap()
+}
+
+// updateInlineEdit handles the key message while editing inline. Unsaved
+// changes are only discarded once confirmed, while ctrl+c still quits.
+func (m *Model) updateInlineEdit(msg tea.KeyMsg) tea.Cmd {
+	if m.editorDiscarding {
+		m.editorDiscarding = false
+		if key.Matches(msg, m.keys.Confirm) {
+			m.stopInlineEdit()
+			return m.updateContent()
+		}
+		return nil
+	}
+	switch {
+	case msg.Type == tea.KeyCtrlC:
+		return m.quit()
+	case key.Matches(msg, m.keys.SaveEdit):
+		return m.saveInlineEdit()
+	case key.Matches(msg, m.keys.DiscardEdit):
+		if m.editorDirty() {
+			m.editorDiscarding = true
+			return nil
+		}
+		m.stopInlineEdit()
+		return m.updateContent()
+	case key.Matches(msg, m.keys.TogglePreview):
+		m.editorPreview = !m.editorPreview
+		return nil
+	}
+	if m.editorPreview {
+		return nil
+	}
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	return cmd
+}
+
+// editorView returns the view of the inline editor, or the highlighted
+// preview of its contents.
+func (m *Model) editorView() string {
+	if !m.editorPreview {
+		return m.editor.View()
+	}
+
+	content := m.editor.Value()
+	highlighted, err := highlightCode(content, m.selectedSnippet().Language, m.config)
+	if err != nil {
+		highlighted = content
+	}
+	lines := strings.Split(strings.ReplaceAll(highlighted, "\t", strings.Repeat(" ", m.config.Preview.tabWidth())), "\n")
+	if len(lines) > m.editor.Height() {
+		lines = lines[:m.editor.Height()]
+	}
+	for i, line := range lines {
+		lines[i] = m.ContentStyle.LineNumber.Render(fmt.Sprintf("%3d ", i+1)) + line
+	}
+	return strings.Join(lines, "\n")
+}
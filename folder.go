@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// trashFolder is the folder in the snippet root that deleted folders are moved
+// to.
+const trashFolder = ".trash"
+
+var (
+	errInvalidFolder = errors.New("invalid folder name")
+	errFolderExists  = errors.New("folder already exists")
+	errNoFolder      = errors.New("no such folder")
+)
+
+// validFolder returns an error if the name cannot be used for a folder.
+func validFolder(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || ignoredFile(name) {
+		return fmt.Errorf("%w: %q", errInvalidFolder, name)
+	}
+	return nil
+}
+
+// folderExists reports whether the folder exists in the snippet root.
+func folderExists(root, folder string) bool {
+	fi, err := os.Stat(filepath.Join(root, folder))
+	return err == nil && fi.IsDir()
+}
+
+// createFolder creates an empty folder in the snippet root.
+func createFolder(root, folder string) error {
+	if err := validFolder(folder); err != nil {
+		return err
+	}
+	if folderExists(root, folder) {
+		return fmt.Errorf("%w: %s", errFolderExists, folder)
+	}
+	return os.MkdirAll(filepath.Join(root, folder), 0755)
+}
+
+// uniqueFile returns a file name in the folder that is not taken yet by
+// adding a numbered suffix to the name of the file if needed.
+func uniqueFile(root, folder, file string) string {
+	s := newSnippet(folder, file)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(root, folder, file)); err != nil {
+			return file
+		}
+		file = s.fileName(fmt.Sprintf("%s-%d", s.Name, i), s.Language)
+	}
+}
+
+// renameFolder moves all snippets of a folder to another folder and removes
+// the old folder. When the other folder already exists the folders are
+// merged, and snippets whose name is taken receive a numbered suffix. Other
+// entries of the folder are moved along unless their name is taken, in which
+// case the old folder is kept for them.
+//
+// It returns the new file names of the snippets that were moved, by their old
+// file names, which holds the snippets moved before an error as well.
+func renameFolder(root, from, to string) (moved map[string]string, err error) {
+	moved = map[string]string{}
+	if err := validFolder(to); err != nil {
+		return moved, err
+	}
+	if from == to {
+		return moved, nil
+	}
+
+	entries, err := os.ReadDir(filepath.Join(root, from))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return moved, err
+	}
+	if !folderExists(root, to) {
+		if err := os.MkdirAll(filepath.Join(root, from), 0755); err != nil {
+			return moved, err
+		}
+		if err := os.Rename(filepath.Join(root, from), filepath.Join(root, to)); err != nil {
+			return moved, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && !ignoredFile(e.Name()) {
+				moved[e.Name()] = e.Name()
+			}
+		}
+		return moved, updateLibrary(root, func(lib Library) {
+			lib.moveFolder(from, to)
+		})
+	}
+
+	lib, err := readLibrary(root)
+	if err != nil {
+		return moved, err
+	}
+	// The metadata of the snippets that were moved is kept even when moving
+	// the others fails.
+	defer func() {
+		if writeErr := lib.write(root); err == nil {
+			err = writeErr
+		}
+	}()
+	for _, e := range entries {
+		if e.IsDir() || ignoredFile(e.Name()) {
+			if _, err := os.Lstat(filepath.Join(root, to, e.Name())); err == nil {
+				continue
+			}
+			if err := os.Rename(filepath.Join(root, from, e.Name()), filepath.Join(root, to, e.Name())); err != nil {
+				return moved, err
+			}
+			continue
+		}
+		file := uniqueFile(root, to, e.Name())
+		if err := os.Rename(filepath.Join(root, from, e.Name()), filepath.Join(root, to, file)); err != nil {
+			return moved, err
+		}
+		moved[e.Name()] = file
+		lib.move(from, e.Name(), to, file)
+	}
+	if left, err := os.ReadDir(filepath.Join(root, from)); err == nil && len(left) > 0 {
+		return moved, nil
+	}
+	return moved, os.Remove(filepath.Join(root, from))
+}
+
+// trashFolderFiles moves the folder to the trash of the snippet root.
+func trashFolderFiles(root, folder string) error {
+	trash := filepath.Join(root, trashFolder)
+	if err := os.MkdirAll(trash, 0755); err != nil {
+		return err
+	}
+	stamp := time.Now().Format("20060102-150405")
+	name := fmt.Sprintf("%s-%s", folder, stamp)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(filepath.Join(trash, name)); err != nil {
+			break
+		}
+		name = fmt.Sprintf("%s-%s-%d", folder, stamp, i)
+	}
+	if err := os.Rename(filepath.Join(root, folder), filepath.Join(trash, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return updateLibrary(root, func(lib Library) {
+		lib.removeFolder(folder)
+	})
+}
+
+// submitFolderPrompt performs the folder action with the folder name that
+// was entered.
+func (m *Model) submitFolderPrompt(action state, name string) tea.Cmd {
+	var err error
+	switch action {
+	case creatingFolderState:
+		err = createFolder(m.config.Root, name)
+		if err == nil {
+			m.Lists[Folder(name)] = newList([]list.Item{}, m.height, m.ListStyle)
+		}
+	case renamingFolderState:
+		err = m.moveFolder(m.selectedFolder(), Folder(name))
+	case mergingFolderState:
+		if _, ok := m.Lists[Folder(name)]; !ok {
+			err = fmt.Errorf("%w: %s", errNoFolder, name)
+		} else {
+			err = m.moveFolder(m.selectedFolder(), Folder(name))
+		}
+	}
+	if err != nil {
+		m.displayError(err.Error())
+		return nil
+	}
+	return tea.Batch(m.selectFolder(Folder(name)), m.updateContent())
+}
+
+// moveFolder moves all snippets of a folder to another folder, merging them if
+// the other folder exists.
+func (m *Model) moveFolder(from, to Folder) error {
+	if from == to {
+		return nil
+	}
+	// Snippets that were moved before an error are moved in the lists as
+	// well, so that they match the files.
+	moved, err := renameFolder(m.config.Root, string(from), string(to))
+	if err != nil && len(moved) <= 0 {
+		return err
+	}
+
+	dst, ok := m.Lists[to]
+	if !ok {
+		dst = newList([]list.Item{}, m.height, m.ListStyle)
+		m.Lists[to] = dst
+	}
+	if src, ok := m.Lists[from]; ok {
+		var kept []list.Item
+		for _, item := range src.Items() {
+			s, ok := item.(Snippet)
+			file, isMoved := moved[s.File]
+			if !ok || !isMoved {
+				kept = append(kept, item)
+				continue
+			}
+			s.Folder, s.File = string(to), file
+			s.Name = newSnippet(s.Folder, file).Name
+			dst.InsertItem(len(dst.Items()), s)
+		}
+		if err != nil {
+			src.SetItems(kept)
+			return err
+		}
+	}
+	delete(m.Lists, from)
+	return err
+}
+
+// deleteFolder moves the selected folder to the trash and removes it from the
+// folders.
+func (m *Model) deleteFolder() tea.Cmd {
+	folder := m.selectedFolder()
+	m.state = navigatingState
+	m.updateKeyMap()
+	if err := trashFolderFiles(m.config.Root, string(folder)); err != nil {
+		m.displayError(err.Error())
+		return nil
+	}
+	return m.removeFolder(folder)
+}
+
+// selectFolder updates the folders and selects the given folder.
+func (m *Model) selectFolder(folder Folder) tea.Cmd {
+	msg := m.updateFoldersView()
+	for i, item := range msg.items {
+		if item == folder {
+			msg.selectedFolderIndex = i
+		}
+	}
+	return m.setFolders(msg)
+}
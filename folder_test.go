@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRenameFolderMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		moved map[string]string
+		// exists and gone are the paths that should and should not exist
+		// after the merge.
+		exists, gone []string
+		lib          Library
+	}{
+		{
+			name: "all entries",
+			files: map[string]string{
+				"misc/a.sh":      "ls\n",
+				"misc/b.txt":     "b\n",
+				"misc/.notes":    "kept along\n",
+				"misc/sub/c.txt": "c\n",
+				"shell/b.txt":    "taken\n",
+				metadataFile:     "misc/a.sh:\n  tags: [cli]\nmisc/b.txt:\n  description: moved b\nshell/b.txt:\n  tags: [old]\n",
+			},
+			moved:  map[string]string{"a.sh": "a.sh", "b.txt": "b-1.txt"},
+			exists: []string{"shell/a.sh", "shell/b-1.txt", "shell/b.txt", "shell/.notes", "shell/sub/c.txt"},
+			gone:   []string{"misc"},
+			lib: Library{
+				"shell/a.sh":    {Tags: []string{"cli"}},
+				"shell/b-1.txt": {Description: "moved b"},
+				"shell/b.txt":   {Tags: []string{"old"}},
+			},
+		},
+		{
+			name: "taken entries",
+			files: map[string]string{
+				"misc/a.sh":       "ls\n",
+				"misc/sub/c.txt":  "c\n",
+				"shell/sub/d.txt": "d\n",
+				metadataFile:      "misc/a.sh:\n  tags: [cli]\n",
+			},
+			moved:  map[string]string{"a.sh": "a.sh"},
+			exists: []string{"shell/a.sh", "misc/sub/c.txt", "shell/sub/d.txt"},
+			gone:   []string{"misc/a.sh", "shell/sub/c.txt"},
+			lib:    Library{"shell/a.sh": {Tags: []string{"cli"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			moved, err := renameFolder(root, "misc", "shell")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(moved, tt.moved) {
+				t.Errorf("moved %v, want %v", moved, tt.moved)
+			}
+			for _, name := range tt.exists {
+				if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+					t.Errorf("%s does not exist: %v", name, err)
+				}
+			}
+			for _, name := range tt.gone {
+				if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+					t.Errorf("%s still exists", name)
+				}
+			}
+			lib, err := readLibrary(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(lib, tt.lib) {
+				t.Errorf("library %v, want %v", lib, tt.lib)
+			}
+		})
+	}
+}
+
+func TestTrashSameName(t *testing.T) {
+	root := t.TempDir()
+	write := func(name string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("ls\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(dir string) int {
+		t.Helper()
+		entries, err := os.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(entries)
+	}
+
+	for i := 0; i < 3; i++ {
+		write("misc/a.sh")
+		if err := trashSnippetFile(root, newSnippet("misc", "a.sh")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := count(filepath.Join(trashFolder, "misc")); n != 3 {
+		t.Errorf("got %d trashed snippets, want 3", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		write("shell/b.sh")
+		if err := trashFolderFiles(root, "shell"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The trash holds the folder of the trashed snippets along with the
+	// trashed folders.
+	if n := count(trashFolder); n != 3 {
+		t.Errorf("got %d entries in the trash, want 3", n)
+	}
+}
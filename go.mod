@@ -2,31 +2,45 @@ module github.com/crispybaccoon/snp
 
 go 1.19
 
-require github.com/charmbracelet/lipgloss v0.6.0
+require (
+	filippo.io/age v1.0.0
+	github.com/adrg/xdg v0.4.0
+	github.com/alecthomas/chroma/v2 v2.4.0
+	github.com/atotto/clipboard v0.1.4
+	github.com/aymanbagabas/go-osc52 v1.0.3
+	github.com/caarlos0/env/v6 v6.10.1
+	github.com/charmbracelet/bubbles v0.14.0
+	github.com/charmbracelet/bubbletea v0.23.1
+	github.com/charmbracelet/glamour v0.6.0
+	github.com/charmbracelet/lipgloss v0.6.0
+	github.com/fsnotify/fsnotify v1.6.0
+	github.com/mattn/go-isatty v0.0.16
+	github.com/mattn/go-runewidth v0.0.14
+	github.com/muesli/termenv v0.13.0
+	github.com/sahilm/fuzzy v0.1.0
+	golang.org/x/exp v0.0.0-20221126150942-6ab00d035af9
+	golang.org/x/sys v0.1.0
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/adrg/xdg v0.4.0 // indirect
-	github.com/alecthomas/chroma/v2 v2.4.0 // indirect
-	github.com/atotto/clipboard v0.1.4 // indirect
-	github.com/aymanbagabas/go-osc52 v1.0.3 // indirect
-	github.com/caarlos0/env/v6 v6.10.1 // indirect
-	github.com/charmbracelet/bubbles v0.14.0 // indirect
-	github.com/charmbracelet/bubbletea v0.23.1 // indirect
+	github.com/alecthomas/chroma v0.10.0 // indirect
+	github.com/aymerick/douceur v0.2.0 // indirect
 	github.com/containerd/console v1.0.3 // indirect
 	github.com/dlclark/regexp2 v1.4.0 // indirect
+	github.com/gorilla/css v1.0.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-isatty v0.0.16 // indirect
 	github.com/mattn/go-localereader v0.0.1 // indirect
-	github.com/mattn/go-runewidth v0.0.14 // indirect
+	github.com/microcosm-cc/bluemonday v1.0.21 // indirect
 	github.com/muesli/ansi v0.0.0-20211018074035-2e021307bc4b // indirect
 	github.com/muesli/cancelreader v0.2.2 // indirect
 	github.com/muesli/reflow v0.3.0 // indirect
-	github.com/muesli/termenv v0.13.0 // indirect
+	github.com/olekukonko/tablewriter v0.0.5 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	github.com/sahilm/fuzzy v0.1.0 // indirect
-	golang.org/x/exp v0.0.0-20221126150942-6ab00d035af9 // indirect
-	golang.org/x/sys v0.1.0 // indirect
-	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211 // indirect
+	github.com/yuin/goldmark v1.5.2 // indirect
+	github.com/yuin/goldmark-emoji v1.0.1 // indirect
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5 // indirect
+	golang.org/x/net v0.0.0-20221002022538-bcab6841153b // indirect
 	golang.org/x/text v0.3.7 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
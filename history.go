@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// historySize is the number of clipboard captures that are kept.
+const historySize = 50
+
+// Clip is a capture of the clipboard.
+type Clip struct {
+	Content string    `yaml:"content"`
+	Time    time.Time `yaml:"time"`
+}
+
+// Preview returns the first line of the clip, shortened to the width.
+func (c Clip) Preview(width int) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(c.Content), "\n", 2)[0])
+	line = strings.ReplaceAll(line, "\t", " ")
+	if r := []rune(line); len(r) > width && width > 1 {
+		line = string(r[:width-1]) + "…"
+	}
+	return line
+}
+
+// History is the ring of recent clipboard captures, newest first.
+type History []Clip
+
+// historyFile returns the path of the history in the state directory.
+func historyFile(stateDir string) string {
+	return filepath.Join(stateDir, "history.yaml")
+}
+
+// readHistory reads the history from the state directory.
+func readHistory(stateDir string) (History, error) {
+	var h History
+	content, err := os.ReadFile(historyFile(stateDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	return h, yaml.Unmarshal(content, &h)
+}
+
+// write writes the history to the state directory.
+func (h History) write(stateDir string) error {
+	content, err := yaml.Marshal(h)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(historyFile(stateDir), content, 0600)
+}
+
+// add returns the history with the content in front. Earlier captures of the
+// same content are dropped and the oldest captures fall off once the history
+// is full.
+func (h History) add(content string, t time.Time) History {
+	if strings.TrimSpace(content) == "" || (len(h) > 0 && h[0].Content == content) {
+		return h
+	}
+	clips := History{{Content: content, Time: t}}
+	for _, c := range h {
+		if c.Content != content {
+			clips = append(clips, c)
+		}
+	}
+	if len(clips) > historySize {
+		clips = clips[:historySize]
+	}
+	return clips
+}
+
+// recordClip adds the content to the history in the state directory.
+func recordClip(stateDir, content string) (History, error) {
+	h, err := readHistory(stateDir)
+	if err != nil {
+		return h, err
+	}
+	h = h.add(content, time.Now())
+	return h, h.write(stateDir)
+}
+
+// captureClipboard adds the contents of the clipboard to the history in the
+// state directory, unless they were copied from sensitive snippets.
+func captureClipboard(cb Clipboard, stateDir string) (History, error) {
+	content, err := cb.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read %s clipboard: %w", cb.Name(), err)
+	}
+	if isSensitiveCopy(stateDir, content) {
+		return readHistory(stateDir)
+	}
+	return recordClip(stateDir, content)
+}
+
+// age returns how long ago the time was in a short form.
+func age(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// historyMsg tells the application that the clipboard history was read.
+type historyMsg History
+
+// captureClipboard returns a Cmd that captures the clipboard into the history
+// and reads the history. When the clipboard cannot be read, the history is
+// read as it is.
+//
+// The clipboard is only captured when the history is opened, as it may hold
+// passwords copied from other applications.
+func (m *Model) captureClipboard() tea.Cmd {
+	return func() tea.Msg {
+		h, err := captureClipboard(m.clipboard, m.config.StateDir)
+		if err != nil {
+			h, _ = readHistory(m.config.StateDir)
+		}
+		return historyMsg(h)
+	}
+}
+
+// pasteMode is how a clip from the history is pasted.
+type pasteMode int
+
+const (
+	appendPaste pasteMode = iota
+	replacePaste
+	newPaste
+)
+
+// startPicking opens the clipboard history in the content pane.
+func (m *Model) startPicking() tea.Cmd {
+	m.historyIndex = 0
+	m.state = pickingState
+	m.pane = contentPane
+	m.updateKeyMap()
+	return m.captureClipboard()
+}
+
+// stopPicking closes the clipboard history.
+func (m *Model) stopPicking() {
+	m.state = navigatingState
+	m.updateKeyMap()
+}
+
+// updatePicker handles the key message while picking from the clipboard
+// history.
+func (m *Model) updatePicker(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keys.PasteAppend):
+		return m.pasteClip(appendPaste)
+	case key.Matches(msg, m.keys.PasteReplace):
+		return m.pasteClip(replacePaste)
+	case key.Matches(msg, m.keys.PasteNew):
+		return m.pasteClip(newPaste)
+	case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
+		m.stopPicking()
+		return m.updateContent()
+	}
+	switch msg.String() {
+	case "up", "k":
+		if m.historyIndex > 0 {
+			m.historyIndex--
+		}
+	case "down", "j":
+		if m.historyIndex < len(m.history)-1 {
+			m.historyIndex++
+		}
+	}
+	return nil
+}
+
+// pasteClip pastes the selected clip of the history into the selected
+// snippet, or into a new snippet if there is none.
+func (m *Model) pasteClip(mode pasteMode) tea.Cmd {
+	if m.historyIndex >= len(m.history) {
+		return nil
+	}
+	clip := m.history[m.historyIndex]
+	m.stopPicking()
+	if m.List().SelectedItem() == nil {
+		mode = newPaste
+	}
+
+	var err error
+	switch mode {
+	case appendPaste:
+		err = m.appendSnippet(m.selectedSnippet(), clip.Content)
+	case replacePaste:
+		err = m.writeSnippet(m.selectedSnippet(), clip.Content)
+	case newPaste:
+		err = m.newSnippetFile(clip.Content)
+	}
+	if err != nil {
+		m.displayError(err.Error())
+		return nil
+	}
+	return m.updateContent()
+}
+
+// historyView returns the view of the clipboard history with the selected
+// clip highlighted.
+func (m *Model) historyView() string {
+	if len(m.history) <= 0 {
+		return m.ContentStyle.EmptyHint.Render("The clipboard history is empty.")
+	}
+
+	start := 0
+	if m.historyIndex >= m.Code.Height && m.Code.Height > 0 {
+		start = m.historyIndex - m.Code.Height + 1
+	}
+	var lines []string
+	for i := start; i < len(m.history) && (m.Code.Height <= 0 || i < start+m.Code.Height); i++ {
+		clip := m.history[i]
+		style := m.ContentStyle.EmptyHint
+		if i == m.historyIndex {
+			style = m.ContentStyle.EmptyHintKey
+		}
+		lines = append(lines, m.ContentStyle.LineNumber.Render(fmt.Sprintf("%3d ", i+1))+
+			style.Render(fmt.Sprintf("%-4s %s", age(clip.Time), clip.Preview(m.Code.Width-5))))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -4,53 +4,145 @@ import "github.com/charmbracelet/bubbles/key"
 
 // KeyMap is the mappings of actions to key bindings.
 type KeyMap struct {
-	Quit          key.Binding
-	Search        key.Binding
-	ToggleHelp    key.Binding
-	NewSnippet    key.Binding
-	DeleteSnippet key.Binding
-	EditSnippet   key.Binding
-	CopySnippet   key.Binding
-	PasteSnippet  key.Binding
-	SetFolder     key.Binding
-	RenameSnippet key.Binding
-	SetLanguage   key.Binding
-	Confirm       key.Binding
-	Cancel        key.Binding
-	NextPane      key.Binding
-	PreviousPane  key.Binding
-	ChangeFolder  key.Binding
+	Quit           key.Binding
+	Search         key.Binding
+	ToggleHelp     key.Binding
+	NewSnippet     key.Binding
+	DeleteSnippet  key.Binding
+	EditSnippet    key.Binding
+	CopySnippet    key.Binding
+	PasteSnippet   key.Binding
+	SetFolder      key.Binding
+	RenameSnippet  key.Binding
+	SetLanguage    key.Binding
+	Confirm        key.Binding
+	Cancel         key.Binding
+	NextPane       key.Binding
+	PreviousPane   key.Binding
+	ChangeFolder   key.Binding
+	NewFolder      key.Binding
+	RenameFolder   key.Binding
+	MergeFolder    key.Binding
+	DeleteFolder   key.Binding
+	MarkSnippet    key.Binding
+	ClearMarks     key.Binding
+	AddTag         key.Binding
+	RemoveTag      key.Binding
+	ExportSnippets key.Binding
+	Overwrite      key.Binding
+	AddSuffix      key.Binding
+	InlineEdit     key.Binding
+	SaveEdit       key.Binding
+	DiscardEdit    key.Binding
+	TogglePreview  key.Binding
+	PasteHistory   key.Binding
+	PasteAppend    key.Binding
+	PasteReplace   key.Binding
+	PasteNew       key.Binding
+	NextTheme      key.Binding
+	GrowPane       key.Binding
+	ShrinkPane     key.Binding
+	ZoomPane       key.Binding
+	Palette        key.Binding
+	RunCommand     key.Binding
+	ClosePalette   key.Binding
+	SortSnippets   key.Binding
+	NextMatch      key.Binding
+	PreviousMatch  key.Binding
+	ScrollLeft     key.Binding
+	ScrollRight    key.Binding
+	ToggleWrap     key.Binding
+	ToggleSpaces   key.Binding
+	ToggleMarkdown key.Binding
+	Run            key.Binding
+	CloseOutput    key.Binding
+	Unlock         key.Binding
+	Encrypt        key.Binding
+	Reveal         key.Binding
 }
 
 // DefaultKeyMap is the default key map for the application.
 var DefaultKeyMap = KeyMap{
-	Quit:          key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "exit")),
-	Search:        key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
-	ToggleHelp:    key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
-	NewSnippet:    key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new")),
-	DeleteSnippet: key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete")),
-	EditSnippet:   key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
-	CopySnippet:   key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
-	PasteSnippet:  key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "paste")),
-	RenameSnippet: key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename snippet")),
-	SetFolder:     key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "rename folder")),
-	SetLanguage:   key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "set file type")),
-	Confirm:       key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
-	Cancel:        key.NewBinding(key.WithKeys("N", "esc"), key.WithHelp("N", "cancel")),
-	NextPane:      key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "navigate")),
-	PreviousPane:  key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "navigate")),
-	ChangeFolder:  key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "change folder"), key.WithDisabled()),
+	Quit:           key.NewBinding(key.WithKeys("q", "ctrl+c"), key.WithHelp("q", "exit")),
+	Search:         key.NewBinding(key.WithKeys("/"), key.WithHelp("/", "search")),
+	ToggleHelp:     key.NewBinding(key.WithKeys("?"), key.WithHelp("?", "help")),
+	NewSnippet:     key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new")),
+	DeleteSnippet:  key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete")),
+	EditSnippet:    key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "edit")),
+	CopySnippet:    key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "copy")),
+	PasteSnippet:   key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "paste")),
+	RenameSnippet:  key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename snippet")),
+	SetFolder:      key.NewBinding(key.WithKeys("R"), key.WithHelp("R", "move to folder")),
+	SetLanguage:    key.NewBinding(key.WithKeys("L"), key.WithHelp("L", "set file type")),
+	Confirm:        key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "confirm")),
+	Cancel:         key.NewBinding(key.WithKeys("N", "esc"), key.WithHelp("N", "cancel")),
+	NextPane:       key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "navigate")),
+	PreviousPane:   key.NewBinding(key.WithKeys("shift+tab"), key.WithHelp("shift+tab", "navigate")),
+	ChangeFolder:   key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "change folder"), key.WithDisabled()),
+	NewFolder:      key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new folder"), key.WithDisabled()),
+	RenameFolder:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "rename folder"), key.WithDisabled()),
+	MergeFolder:    key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "merge folder"), key.WithDisabled()),
+	DeleteFolder:   key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "delete folder"), key.WithDisabled()),
+	MarkSnippet:    key.NewBinding(key.WithKeys(" "), key.WithHelp("space", "mark")),
+	ClearMarks:     key.NewBinding(key.WithKeys("U"), key.WithHelp("U", "unmark all"), key.WithDisabled()),
+	AddTag:         key.NewBinding(key.WithKeys("t"), key.WithHelp("t", "add tag")),
+	RemoveTag:      key.NewBinding(key.WithKeys("T"), key.WithHelp("T", "remove tag")),
+	ExportSnippets: key.NewBinding(key.WithKeys("E"), key.WithHelp("E", "export")),
+	Overwrite:      key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "overwrite")),
+	AddSuffix:      key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "add suffix")),
+	InlineEdit:     key.NewBinding(key.WithKeys("i"), key.WithHelp("i", "edit inline")),
+	SaveEdit:       key.NewBinding(key.WithKeys("ctrl+s"), key.WithHelp("ctrl+s", "save"), key.WithDisabled()),
+	DiscardEdit:    key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "discard"), key.WithDisabled()),
+	TogglePreview:  key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "preview"), key.WithDisabled()),
+	PasteHistory:   key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "paste from history")),
+	PasteAppend:    key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "append"), key.WithDisabled()),
+	PasteReplace:   key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "replace"), key.WithDisabled()),
+	PasteNew:       key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new snippet"), key.WithDisabled()),
+	NextTheme:      key.NewBinding(key.WithKeys("ctrl+t"), key.WithHelp("ctrl+t", "next theme")),
+	GrowPane:       key.NewBinding(key.WithKeys(">"), key.WithHelp(">", "widen pane")),
+	ShrinkPane:     key.NewBinding(key.WithKeys("<"), key.WithHelp("<", "narrow pane")),
+	ZoomPane:       key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "zoom")),
+	Palette:        key.NewBinding(key.WithKeys(":", "ctrl+p"), key.WithHelp(":", "command palette")),
+	RunCommand:     key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "run"), key.WithDisabled()),
+	ClosePalette:   key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "close"), key.WithDisabled()),
+	SortSnippets:   key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "sort")),
+	NextMatch:      key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "next match"), key.WithDisabled()),
+	PreviousMatch:  key.NewBinding(key.WithKeys("N"), key.WithHelp("N", "previous match"), key.WithDisabled()),
+	ScrollLeft:     key.NewBinding(key.WithKeys("h", "left"), key.WithHelp("h", "scroll left"), key.WithDisabled()),
+	ScrollRight:    key.NewBinding(key.WithKeys("l", "right"), key.WithHelp("l", "scroll right"), key.WithDisabled()),
+	ToggleWrap:     key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "wrap lines")),
+	ToggleSpaces:   key.NewBinding(key.WithKeys("W"), key.WithHelp("W", "show whitespace")),
+	ToggleMarkdown: key.NewBinding(key.WithKeys("M"), key.WithHelp("M", "render markdown"), key.WithDisabled()),
+	Run:            key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "run")),
+	CloseOutput:    key.NewBinding(key.WithKeys("esc", "q"), key.WithHelp("esc", "close output"), key.WithDisabled()),
+	Unlock:         key.NewBinding(key.WithKeys("O"), key.WithHelp("O", "unlock"), key.WithDisabled()),
+	Encrypt:        key.NewBinding(key.WithKeys("ctrl+e"), key.WithHelp("ctrl+e", "encrypt")),
+	Reveal:         key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "reveal"), key.WithDisabled()),
 }
 
 // ShortHelp returns a quick help menu.
 func (k KeyMap) ShortHelp() []key.Binding {
 	return []key.Binding{
+		k.SaveEdit,
+		k.DiscardEdit,
+		k.TogglePreview,
+		k.PasteAppend,
+		k.PasteReplace,
+		k.PasteNew,
+		k.RunCommand,
+		k.ClosePalette,
+		k.CloseOutput,
 		k.NextPane,
 		k.Search,
+		k.NextMatch,
+		k.Unlock,
+		k.Reveal,
 		k.EditSnippet,
 		k.DeleteSnippet,
 		k.CopySnippet,
 		k.NewSnippet,
+		k.NewFolder,
+		k.DeleteFolder,
 		k.ToggleHelp,
 	}
 }
@@ -58,9 +150,51 @@ func (k KeyMap) ShortHelp() []key.Binding {
 // FullHelp returns all help options in a more detailed view.
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.NewSnippet, k.EditSnippet, k.PasteSnippet, k.CopySnippet, k.DeleteSnippet},
-		{k.RenameSnippet, k.SetFolder, k.SetLanguage},
-		{k.NextPane, k.PreviousPane},
-		{k.Search, k.ToggleHelp, k.Quit},
+		k.navigationKeys(),
+		k.snippetKeys(),
+		k.fileKeys(),
+		k.folderKeys(),
+		// The pane keys share a column to keep the help within the width of
+		// the terminal.
+		append(k.markKeys(), k.paneKeys()...),
+		k.contentKeys(),
 	}
 }
+
+// navigationKeys returns the bindings to move around and leave the
+// application.
+func (k KeyMap) navigationKeys() []key.Binding {
+	return []key.Binding{k.NextPane, k.PreviousPane, k.ZoomPane, k.Search, k.Palette, k.NextTheme, k.ToggleHelp, k.Quit}
+}
+
+// snippetKeys returns the bindings of the actions on the selected snippet.
+func (k KeyMap) snippetKeys() []key.Binding {
+	return []key.Binding{k.NewSnippet, k.EditSnippet, k.InlineEdit, k.PasteSnippet, k.PasteHistory, k.CopySnippet, k.DeleteSnippet, k.Run}
+}
+
+// fileKeys returns the bindings that change the file of the selected
+// snippet.
+func (k KeyMap) fileKeys() []key.Binding {
+	return []key.Binding{k.RenameSnippet, k.SetFolder, k.SetLanguage, k.Encrypt, k.Unlock}
+}
+
+// folderKeys returns the bindings of the folder actions.
+func (k KeyMap) folderKeys() []key.Binding {
+	return []key.Binding{k.NewFolder, k.RenameFolder, k.MergeFolder, k.DeleteFolder}
+}
+
+// markKeys returns the bindings to mark snippets and act on the marked
+// snippets.
+func (k KeyMap) markKeys() []key.Binding {
+	return []key.Binding{k.MarkSnippet, k.ClearMarks, k.AddTag, k.RemoveTag, k.ExportSnippets, k.SortSnippets}
+}
+
+// paneKeys returns the bindings that resize the panes.
+func (k KeyMap) paneKeys() []key.Binding {
+	return []key.Binding{k.ShrinkPane, k.GrowPane}
+}
+
+// contentKeys returns the bindings of the content pane.
+func (k KeyMap) contentKeys() []key.Binding {
+	return []key.Binding{k.NextMatch, k.PreviousMatch, k.ScrollLeft, k.ScrollRight, k.ToggleWrap, k.ToggleSpaces, k.ToggleMarkdown, k.Reveal}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// collapseWidth is the width of the terminal below which the folder pane
+	// is only shown while it is focused, in place of the snippet pane.
+	collapseWidth = 90
+	// stackWidth is the width of the terminal below which the panes are
+	// stacked on top of each other.
+	stackWidth = 60
+
+	minFoldersWidth  = 14
+	minSnippetsWidth = 24
+	minContentWidth  = 30
+
+	// resizeStep is the number of columns that a pane is resized by.
+	resizeStep = 2
+)
+
+// Layout holds the layout preferences of the panes, which are kept in the
+// state directory.
+type Layout struct {
+	FoldersWidth  int  `yaml:"folders_width"`
+	SnippetsWidth int  `yaml:"snippets_width"`
+	Zoomed        bool `yaml:"zoomed"`
+}
+
+// defaultLayout is the layout that is used without any preferences.
+var defaultLayout = Layout{FoldersWidth: 22, SnippetsWidth: 35}
+
+// layoutFile returns the path of the layout in the state directory.
+func layoutFile(stateDir string) string {
+	return filepath.Join(stateDir, "layout.yaml")
+}
+
+// readLayout reads the layout from the state directory, falling back to the
+// default layout.
+func readLayout(stateDir string) (Layout, error) {
+	l := defaultLayout
+	content, err := os.ReadFile(layoutFile(stateDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	if err := yaml.Unmarshal(content, &l); err != nil {
+		return defaultLayout, err
+	}
+	return l.clamp(), nil
+}
+
+// write writes the layout to the state directory.
+func (l Layout) write(stateDir string) error {
+	content, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(layoutFile(stateDir), content, 0644)
+}
+
+// clamp returns the layout with the pane widths no smaller than their
+// minimums.
+func (l Layout) clamp() Layout {
+	if l.FoldersWidth < minFoldersWidth {
+		l.FoldersWidth = minFoldersWidth
+	}
+	if l.SnippetsWidth < minSnippetsWidth {
+		l.SnippetsWidth = minSnippetsWidth
+	}
+	return l
+}
+
+// geometry is the size of the panes in the terminal. Panes that are hidden
+// have no width.
+type geometry struct {
+	folders       int
+	snippets      int
+	content       int
+	listHeight    int
+	contentHeight int
+	stacked       bool
+}
+
+// geometry returns the size of the panes for a terminal of the width, where
+// height is the height available to the lists, and the focused pane.
+//
+// The content pane takes the width that is left by the folder and snippet
+// panes, which give up columns when it would get too narrow. On narrow
+// terminals the folder pane is collapsed, and on very narrow terminals the
+// focused list is stacked on top of the content pane. A zoomed layout only
+// shows the content pane while it is focused.
+func (l Layout) geometry(width, height int, focused pane) geometry {
+	g := geometry{listHeight: height, contentHeight: height}
+	switch {
+	case l.Zoomed && focused == contentPane:
+		g.content = width
+	case width > 0 && width < stackWidth:
+		g.stacked = true
+		if focused == folderPane {
+			g.folders = width
+		} else {
+			g.snippets = width
+		}
+		g.content = width
+		// The title bars of the list and the content take two lines each.
+		g.listHeight = height / 2
+		g.contentHeight = height - g.listHeight - 2
+	case width > 0 && width < collapseWidth:
+		w := l.SnippetsWidth
+		if width-w < minContentWidth {
+			w = max(width-minContentWidth, minSnippetsWidth)
+		}
+		if focused == folderPane {
+			g.folders = w
+		} else {
+			g.snippets = w
+		}
+		g.content = width - w
+	default:
+		g.folders, g.snippets = l.FoldersWidth, l.SnippetsWidth
+		if width > 0 {
+			for excess := g.folders + g.snippets + minContentWidth - width; excess > 0; excess-- {
+				if g.snippets > minSnippetsWidth {
+					g.snippets--
+				} else if g.folders > minFoldersWidth {
+					g.folders--
+				} else {
+					break
+				}
+			}
+			g.content = width - g.folders - g.snippets
+		}
+	}
+	return g
+}
+
+// max returns the larger of the two numbers.
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// geometry returns the size of the panes for the size of the terminal.
+func (m *Model) geometry() geometry {
+	height := m.height
+	if m.help.ShowAll {
+		// The full help takes the rows of its longest column in place of the
+		// single row of the short help.
+		height -= lipgloss.Height(m.help.View(m.keys)) - 1
+	}
+	return m.layout.geometry(m.width, height, m.pane)
+}
+
+// resize sizes the lists, the content and the editor to the layout.
+func (m *Model) resize() {
+	g := m.geometry()
+	for _, li := range m.Lists {
+		li.SetHeight(g.listHeight)
+		if g.snippets > 0 {
+			li.SetWidth(g.snippets - 10)
+			li.Styles.StatusBar = li.Styles.StatusBar.MaxWidth(g.snippets - 2)
+			li.Styles.NoItems = li.Styles.NoItems.MaxWidth(g.snippets - 2)
+		}
+	}
+	m.Folders.SetHeight(g.listHeight)
+	m.Code.Height = g.contentHeight
+	m.LineNumbers.Height = g.contentHeight
+	m.LineNumbers.Width = 5
+	width := m.Code.Width
+	m.Code.Width = max(g.content-m.LineNumbers.Width-3, 10)
+	if m.Code.Width != width && m.highlighted != "" {
+		// Markdown is rendered to the width of the pane.
+		if m.rendersMarkdown() {
+			_ = m.highlightContent()
+		} else if m.wrap {
+			m.renderContent()
+		}
+	}
+	m.editor.SetWidth(m.Code.Width + m.LineNumbers.Width)
+	m.editor.SetHeight(g.contentHeight)
+}
+
+// resizePane widens the focused pane by the number of columns, or narrows it
+// when negative. Widening the content pane narrows the snippet pane.
+func (m *Model) resizePane(delta int) tea.Cmd {
+	l := m.layout
+	switch m.pane {
+	case folderPane:
+		l.FoldersWidth += delta
+	case snippetPane:
+		l.SnippetsWidth += delta
+	case contentPane:
+		l.SnippetsWidth -= delta
+	}
+	l = l.clamp()
+	if l.FoldersWidth+l.SnippetsWidth+minContentWidth > m.width && l.FoldersWidth+l.SnippetsWidth > m.layout.FoldersWidth+m.layout.SnippetsWidth {
+		// The content pane is as narrow as it gets.
+		return nil
+	}
+	m.layout = l
+	m.resize()
+	m.updateStyles()
+	return m.saveLayout()
+}
+
+// toggleZoom maximizes the content pane, focusing it, or restores the panes
+// when it is maximized.
+func (m *Model) toggleZoom() tea.Cmd {
+	m.layout.Zoomed = !m.layout.Zoomed
+	if m.layout.Zoomed {
+		m.pane = contentPane
+	}
+	m.updateKeyMap()
+	m.resize()
+	m.updateStyles()
+	return m.saveLayout()
+}
+
+// saveLayout returns a Cmd that writes the layout to the state directory.
+func (m *Model) saveLayout() tea.Cmd {
+	l := m.layout
+	return func() tea.Msg {
+		if err := l.write(m.config.StateDir); err != nil {
+			return errorMsg{err}
+		}
+		return nil
+	}
+}
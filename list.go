@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -10,13 +11,14 @@ import (
 
 // FilterValue is the snippet filter value that can be used when searching.
 func (s Snippet) FilterValue() string {
-	return s.Folder + "/" + s.Name + "." + s.Language
+	return strings.Join(append([]string{s.Folder + "/" + s.Name + "." + s.Language}, s.Tags...), " ")
 }
 
 // snippetDelegate represents the snippet list item.
 type snippetDelegate struct {
 	styles SnippetsBaseStyle
 	state  state
+	marked map[string]Snippet
 }
 
 // Height is the number of lines the snippet list item takes up.
@@ -61,13 +63,23 @@ func (d snippetDelegate) Render(w io.Writer, m list.Model, index int, item list.
 		subtitleStyle = d.styles.DeletedSubtitle
 	}
 
+	prefix := "  "
+	if _, ok := d.marked[metadataKey(s.Folder, s.File)]; ok {
+		prefix = d.styles.Mark.Render("* ")
+	}
+
+	subtitle := s.Folder + " • " + s.Language
+	if s.Encrypted {
+		subtitle += " • encrypted"
+	}
+
 	if index == m.Index() {
-		fmt.Fprintln(w, "  "+titleStyle.Render(s.Name))
-		fmt.Fprint(w, "  "+subtitleStyle.Render(s.Folder + " • " + s.Language))
+		fmt.Fprintln(w, prefix+titleStyle.Render(s.Name))
+		fmt.Fprint(w, "  "+subtitleStyle.Render(subtitle))
 		return
 	}
-	fmt.Fprintln(w, "  "+d.styles.UnselectedTitle.Render(s.Name))
-	fmt.Fprint(w, "  "+d.styles.UnselectedSubtitle.Render(s.Folder + " • " + s.Language))
+	fmt.Fprintln(w, prefix+d.styles.UnselectedTitle.Render(s.Name))
+	fmt.Fprint(w, "  "+d.styles.UnselectedSubtitle.Render(subtitle))
 }
 
 // Folder represents a group of snippets in a directory.
@@ -105,9 +117,8 @@ func (d folderDelegate) Render(w io.Writer, m list.Model, index int, item list.I
 	}
 	fmt.Fprint(w, "  ")
 	if index == m.Index() {
-		fmt.Fprint(w, d.styles.Selected.Render("• " + string(f)))
+		fmt.Fprint(w, d.styles.Selected.Render("• "+string(f)))
 		return
 	}
-	fmt.Fprint(w, d.styles.Unselected.Render("  " + string(f)))
+	fmt.Fprint(w, d.styles.Unselected.Render("  "+string(f)))
 }
-
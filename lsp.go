@@ -0,0 +1,382 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Error codes of JSON-RPC used by the language server.
+const (
+	rpcParseError     = -32700
+	rpcInvalidParams  = -32602
+	rpcMethodNotFound = -32601
+)
+
+// maxMessageSize limits the size of the messages the language server reads.
+const maxMessageSize = 4 << 20
+
+// Kinds and formats of LSP completion items.
+const (
+	lspSnippetKind   = 15
+	lspSnippetFormat = 2
+)
+
+// lspLanguages maps the language identifiers of LSP that chroma does not
+// know to the languages of snippets.
+var lspLanguages = map[string]string{
+	"shellscript":     "sh",
+	"javascriptreact": "jsx",
+	"typescriptreact": "tsx",
+	"plaintext":       "txt",
+}
+
+// lspEscaper escapes the characters that have a meaning in the snippet
+// syntax of LSP.
+var lspEscaper = strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`)
+
+// rpcMessage is a JSON-RPC request or notification. Notifications have no
+// ID.
+type rpcMessage struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+}
+
+// rpcResponse is the response to a JSON-RPC request.
+type rpcResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+// rpcError is the error of a JSON-RPC request.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string { return e.Message }
+
+// lspDocument identifies a text document in LSP requests.
+type lspDocument struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+}
+
+// lspDocumentParams are the parameters of requests and notifications about
+// a text document.
+type lspDocumentParams struct {
+	TextDocument lspDocument `json:"textDocument"`
+}
+
+// lspMarkup is the Markdown documentation of a completion item.
+type lspMarkup struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// lspCompletionItem is a snippet offered as a completion.
+type lspCompletionItem struct {
+	Label            string    `json:"label"`
+	Kind             int       `json:"kind"`
+	Detail           string    `json:"detail"`
+	Documentation    lspMarkup `json:"documentation"`
+	InsertText       string    `json:"insertText"`
+	InsertTextFormat int       `json:"insertTextFormat"`
+}
+
+// lspSnippet is a snippet along with what the completions show of it.
+type lspSnippet struct {
+	Snippet
+	content     string
+	description string
+}
+
+// lspServer is a language server that completes the snippets of the
+// language of the documents.
+type lspServer struct {
+	config Config
+	in     *bufio.Reader
+	out    io.Writer
+
+	// languages maps the URI of the open documents to their language.
+	languages map[string]string
+	shutdown  bool
+
+	mu       sync.Mutex
+	snippets []lspSnippet
+}
+
+// newLSPServer returns a language server that speaks the protocol on the
+// reader and writer.
+func newLSPServer(config Config, in io.Reader, out io.Writer) *lspServer {
+	l := &lspServer{
+		config:    config,
+		in:        bufio.NewReader(in),
+		out:       out,
+		languages: map[string]string{},
+	}
+	l.reload()
+	return l
+}
+
+// reload reads the snippets again. Encrypted snippets are left out, as
+// there is no asking for a passphrase while speaking the protocol.
+func (l *lspServer) reload() {
+	lib, _ := readLibrary(l.config.Root)
+	var snippets []lspSnippet
+	for _, s := range readSnippets(l.config) {
+		if s.Encrypted {
+			continue
+		}
+		content, err := readSnippetFile(l.config, s, nil)
+		if err != nil {
+			continue
+		}
+		description := lib[metadataKey(s.Folder, s.File)].Description
+		snippets = append(snippets, lspSnippet{s, content, description})
+	}
+	l.mu.Lock()
+	l.snippets = snippets
+	l.mu.Unlock()
+}
+
+// watch reloads the snippets whenever the snippet root changes, until the
+// watcher is closed.
+func (l *lspServer) watch(w *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() && filepath.Dir(event.Name) == filepath.Clean(l.config.Root) {
+				_ = w.Add(event.Name)
+			}
+			l.reload()
+		case _, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// serve handles the messages until the client exits or closes the input.
+func (l *lspServer) serve() error {
+	for {
+		msg, err := l.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if err := l.respond(nil, nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if !l.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+
+		result, err := l.handle(msg)
+		if msg.ID == nil {
+			continue
+		}
+		if !errors.As(err, &rpcErr) && err != nil {
+			rpcErr = &rpcError{rpcInvalidParams, err.Error()}
+		}
+		if err := l.respond(msg.ID, result, rpcErr); err != nil {
+			return err
+		}
+	}
+}
+
+// read returns the next message, which is preceded by its Content-Length
+// header.
+func (l *lspServer) read() (rpcMessage, error) {
+	var msg rpcMessage
+	header, err := textproto.NewReader(l.in).ReadMIMEHeader()
+	if err != nil {
+		return msg, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return msg, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+	if length < 0 {
+		return msg, &rpcError{rpcParseError, fmt.Sprintf("invalid Content-Length: %d", length)}
+	}
+	if length > maxMessageSize {
+		// Skip the message, so that the next one can still be read.
+		if _, err := io.CopyN(io.Discard, l.in, int64(length)); err != nil {
+			return msg, err
+		}
+		return msg, &rpcError{rpcParseError, fmt.Sprintf("message too large: %d bytes", length)}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(l.in, body); err != nil {
+		return msg, err
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return msg, &rpcError{rpcParseError, err.Error()}
+	}
+	return msg, nil
+}
+
+// respond writes the response to the request with the ID.
+func (l *lspServer) respond(id *json.RawMessage, result interface{}, rpcErr *rpcError) error {
+	resp := rpcResponse{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		resp.Result = b
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(l.out, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+// handle returns the result of the request, or handles the notification.
+func (l *lspServer) handle(msg rpcMessage) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   map[string]interface{}{"openClose": true, "change": 0},
+				"completionProvider": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "snp"},
+		}, nil
+	case "shutdown":
+		l.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params lspDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		l.languages[params.TextDocument.URI] = params.TextDocument.LanguageID
+		return nil, nil
+	case "textDocument/didClose":
+		var params lspDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(l.languages, params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/completion":
+		var params lspDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"isIncomplete": false,
+			"items":        l.complete(params.TextDocument.URI),
+		}, nil
+	}
+	if msg.ID == nil {
+		return nil, nil
+	}
+	return nil, &rpcError{rpcMethodNotFound, "method not found: " + msg.Method}
+}
+
+// complete returns the completions for the document: the snippets of its
+// language, which is taken from the extension of the document if the client
+// did not tell it. Sensitive snippets are left out, as completing them would
+// put their contents in the document.
+func (l *lspServer) complete(uri string) []lspCompletionItem {
+	language := l.languages[uri]
+	if language == "" {
+		language = strings.TrimPrefix(filepath.Ext(uri), ".")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	items := []lspCompletionItem{}
+	for _, s := range l.snippets {
+		if !matchesLanguage(language, s.Language) || l.config.isSensitive(s.Snippet) {
+			continue
+		}
+		doc := fmt.Sprintf("```%s\n%s\n```", s.Language, strings.TrimRight(s.content, "\n"))
+		if s.description != "" {
+			doc = s.description + "\n\n" + doc
+		}
+		items = append(items, lspCompletionItem{
+			Label:            s.Name,
+			Kind:             lspSnippetKind,
+			Detail:           s.String(),
+			Documentation:    lspMarkup{"markdown", doc},
+			InsertText:       lspSnippetText(s.content),
+			InsertTextFormat: lspSnippetFormat,
+		})
+	}
+	return items
+}
+
+// matchesLanguage reports whether snippets of the language are completed in
+// documents with the LSP language identifier, comparing them by the chroma
+// lexer for them if they differ.
+func matchesLanguage(languageID, language string) bool {
+	if l, ok := lspLanguages[languageID]; ok {
+		languageID = l
+	}
+	if languageID == "" || strings.EqualFold(languageID, language) {
+		return languageID != ""
+	}
+	a, b := lexers.Get(languageID), lexers.Get(language)
+	return a != nil && b != nil && a.Config().Name == b.Config().Name
+}
+
+// lspSnippetText returns the content in the snippet syntax of LSP. The
+// placeholders become tab stops holding their default values, and repeated
+// placeholders mirror the first one.
+func lspSnippetText(content string) string {
+	var (
+		b     strings.Builder
+		stops = map[string]int{}
+		last  int
+	)
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(content, -1) {
+		b.WriteString(lspEscaper.Replace(content[last:loc[0]]))
+		last = loc[1]
+		name := content[loc[2]:loc[3]]
+		if n, ok := stops[name]; ok {
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		stops[name] = len(stops) + 1
+		var value string
+		if loc[4] >= 0 {
+			value = content[loc[4]:loc[5]]
+		}
+		fmt.Fprintf(&b, "${%d:%s}", stops[name], lspEscaper.Replace(value))
+	}
+	b.WriteString(lspEscaper.Replace(content[last:]))
+	return b.String()
+}
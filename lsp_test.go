@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+)
+
+func TestLSPSnippetText(t *testing.T) {
+	got := lspSnippetText("echo {{greeting:hi}} {{name}} ${HOME} {{greeting}}\n")
+	want := "echo ${1:hi} ${2:} \\${HOME\\} $1\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLSP(t *testing.T) {
+	config, _ := testSnippets(t, map[string]string{
+		"shell/greet.sh": "echo {{greeting:hello}}\n",
+		"shell/token.sh": "export TOKEN=secret\n",
+		".snp.yaml":      "shell/greet.sh:\n  description: Greets\nshell/token.sh:\n  sensitive: true\n",
+	})
+
+	var in bytes.Buffer
+	write := func(id int, method string, params interface{}) {
+		msg := map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+		if id > 0 {
+			msg["id"] = id
+		}
+		b, err := json.Marshal(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	}
+	document := func(uri, language string) map[string]interface{} {
+		return map[string]interface{}{"textDocument": map[string]string{"uri": uri, "languageId": language}}
+	}
+	write(1, "initialize", map[string]interface{}{})
+	write(0, "initialized", map[string]interface{}{})
+	write(0, "textDocument/didOpen", document("file:///tmp/a.sh", "shellscript"))
+	write(2, "textDocument/completion", document("file:///tmp/a.sh", ""))
+	write(3, "textDocument/completion", document("file:///tmp/b.go", ""))
+	write(4, "textDocument/completion", document("file:///tmp/c.rs", ""))
+	write(5, "unknown", nil)
+	// Messages with a negative or too large Content-Length are rejected,
+	// and the ones after them are still read.
+	fmt.Fprintf(&in, "Content-Length: -1\r\n\r\n")
+	fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", maxMessageSize+1, bytes.Repeat([]byte(" "), maxMessageSize+1))
+	write(6, "shutdown", nil)
+	write(0, "exit", nil)
+
+	var out bytes.Buffer
+	if err := newLSPServer(config, &in, &out).serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		ID     int `json:"id"`
+		Result struct {
+			Items []lspCompletionItem `json:"items"`
+		} `json:"result"`
+		Error *rpcError `json:"error"`
+	}
+	var results []result
+	r := bufio.NewReader(&out)
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, n)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatal(err)
+		}
+		var res result
+		if err := json.Unmarshal(body, &res); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, res)
+	}
+	if len(results) != 8 {
+		t.Fatalf("got %d responses, want 8", len(results))
+	}
+
+	labels := func(res result) []string {
+		var labels []string
+		for _, item := range res.Result.Items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+	if got := fmt.Sprint(labels(results[1])); got != "[greet list]" {
+		t.Errorf("shell completions: got %s", got)
+	}
+	if got := fmt.Sprint(labels(results[2])); got != "[hello]" {
+		t.Errorf("go completions: got %s", got)
+	}
+	if got := len(results[3].Result.Items); got != 0 {
+		t.Errorf("rust completions: got %d, want none", got)
+	}
+	greet := results[1].Result.Items[0]
+	if greet.InsertText != "echo ${1:hello}\n" || greet.InsertTextFormat != lspSnippetFormat {
+		t.Errorf("insert text: got %q", greet.InsertText)
+	}
+	if want := "Greets\n\n```sh\necho {{greeting:hello}}\n```"; greet.Documentation.Value != want {
+		t.Errorf("documentation: got %q, want %q", greet.Documentation.Value, want)
+	}
+	if results[4].Error == nil || results[4].Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method: got %+v", results[4].Error)
+	}
+	for _, res := range results[5:7] {
+		if res.Error == nil || res.Error.Code != rpcParseError {
+			t.Errorf("invalid Content-Length: got %+v", res.Error)
+		}
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/mattn/go-isatty"
 	"github.com/sahilm/fuzzy"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
@@ -29,19 +28,68 @@ import (
 func main() {
 	config := readConfig()
 	snippets := readSnippets(config)
-	stdin := readStdin()
-	if stdin != "" {
-		saveSnippet(stdin, config, snippets)
-		return
-	}
 
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
+		case "add":
+			if err := addCommand(config, os.Args[2:], readStdin()); err != nil {
+				exitWithError(err)
+			}
 		case "list":
-			listSnippets(snippets)
+			if err := listCommand(config, snippets, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "mv":
+			if err := moveCommand(config, snippets, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "themes":
+			if err := themesCommand(config, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "clip":
+			if err := clipCommand(config, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "run":
+			if err := runCommand(config, snippets, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "fmt":
+			if err := fmtCommand(config, snippets, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "lint":
+			if err := lintCommand(config, snippets, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "show":
+			if err := showCommand(config, snippets, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "serve":
+			if err := serveCommand(config, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "lsp":
+			if err := lspCommand(config, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+		case "export":
+			if err := exportCommand(config, snippets, os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
 		default:
-			snippet := findSnippet(os.Args[1], snippets)
-			fmt.Print(snippet.Content(isatty.IsTerminal(os.Stdout.Fd())))
+			if err := defaultCommand(config, snippets, os.Args[1:], readStdin()); err != nil {
+				exitWithError(err)
+			}
+		}
+		return
+	}
+
+	if stdinPiped() {
+		if err := addCommand(config, nil, readStdin()); err != nil {
+			exitWithError(err)
 		}
 		return
 	}
@@ -52,49 +100,16 @@ func main() {
 	}
 }
 
-// parseName returns a folder, name, and language for the given name.
-// this is useful for parsing file names when passed as command line arguments.
-//
-// Example:
-//
-//	Notes/Hello.go -> (Notes, Hello, go)
-//	Hello.go       -> (Misc, Hello, go)
-//	Notes/Hello    -> (Notes, Hello, go)
-func parseName(s string) (string, string, string) {
-	var (
-		folder    = defaultSnippetFolder
-		name      = defaultSnippetName
-		language  = defaultLanguage
-		remaining string
-	)
-
-	tokens := strings.Split(s, "/")
-	if len(tokens) > 1 {
-		folder = tokens[0]
-		remaining = tokens[1]
-	} else {
-		remaining = tokens[0]
-	}
-
-	tokens = strings.Split(remaining, ".")
-	if len(tokens) > 1 {
-		name = tokens[0]
-		language = tokens[1]
-	} else {
-		name = tokens[0]
-	}
-
-	return folder, name, language
+// stdinPiped reports whether something is piped in to the command line
+// interface.
+func stdinPiped() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice == 0
 }
 
 // readStdin returns the stdin that is piped in to the command line interface.
 func readStdin() string {
-	stat, err := os.Stdin.Stat()
-	if err != nil {
-		return ""
-	}
-
-	if stat.Mode()&os.ModeCharDevice != 0 {
+	if !stdinPiped() {
 		return ""
 	}
 
@@ -145,7 +160,10 @@ func readConfig() Config {
 		return newConfig()
 	}
 
-	return config
+	if c, err := config.withTheme(config.Theme); err == nil {
+		config = c
+	}
+	return config.adaptToTerminal()
 }
 
 // TODO:
@@ -158,24 +176,14 @@ func readSnippets(config Config) []Snippet {
 	}
 
 	parseFile := func(d fs.FileInfo, p string) {
-		if d.IsDir() {
+		if d.IsDir() || ignoredFile(d.Name()) {
 			return
 		}
-		fname := d.Name()
-		str := strings.Split(fname, ".")
-		var name string
-		lan := "txt"
-		if len(str) < 2 {
-			name = str[0]
-		} else {
-			name = strings.Join(str[:len(str)-1], ".")
-			lan = str[len(str)-1]
-		}
-		snippets = append(snippets, Snippet{Name: name, Folder: p, Language: lan})
+		snippets = append(snippets, newSnippet(p, d.Name()))
 	}
 
 	parseDir := func(d fs.FileInfo) {
-		if !d.IsDir() {
+		if !d.IsDir() || ignoredFile(d.Name()) {
 			return
 		}
 		fdd, err := ioutil.ReadDir(filepath.Join(config.Root, d.Name()))
@@ -196,29 +204,14 @@ func readSnippets(config Config) []Snippet {
 			parseDir(d)
 		}
 	}
-	return snippets
-}
-
-func saveSnippet(content string, config Config, snippets []Snippet) {
-	// Save snippet to location
-	var name string = defaultSnippetName
-	if len(os.Args) > 1 {
-		name = strings.Join(os.Args[1:], " ")
-	}
-
-	folder, name, language := parseName(name)
-	file := fmt.Sprintf("%s.%s", name, language)
-	err := os.WriteFile(filepath.Join(config.Root, folder, file), []byte(content), 0644)
-	if err != nil {
-		fmt.Println("unable to create snippet")
-		return
-	}
-}
 
-func listSnippets(snippets []Snippet) {
-	for _, snippet := range snippets {
-		fmt.Println(snippet)
+	lib, _ := readLibrary(config.Root)
+	for i, s := range snippets {
+		md := lib[metadataKey(s.Folder, s.File)]
+		snippets[i].Tags = md.Tags
+		snippets[i].Sensitive = md.Sensitive
 	}
+	return snippets
 }
 
 // Snippets is a wrapper for a snippets array to implement the fuzzy.Source
@@ -238,7 +231,7 @@ func (s Snippets) Len() int {
 }
 
 func findSnippet(search string, snippets []Snippet) Snippet {
-	matches := fuzzy.FindFrom(os.Args[1], Snippets{snippets})
+	matches := fuzzy.FindFrom(search, Snippets{snippets})
 	if len(matches) > 0 {
 		return snippets[matches[0].Index]
 	}
@@ -246,6 +239,41 @@ func findSnippet(search string, snippets []Snippet) Snippet {
 }
 
 func runInteractiveMode(config Config, snippets []Snippet) error {
+	m := newModel(config, snippets)
+	if w, err := newWatcher(config.Root); err == nil {
+		m.watcher = w
+		defer w.Close()
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	model, err := p.Run()
+	if err != nil {
+		return err
+	}
+	fm, ok := model.(*Model)
+	if !ok {
+		return err
+	}
+	var allSnippets []list.Item
+	for _, list := range fm.Lists {
+		allSnippets = append(allSnippets, list.Items()...)
+	}
+	if len(allSnippets) <= 0 {
+		allSnippets = []list.Item{defaultSnippet}
+	}
+	/* b, err := json.Marshal(allSnippets)
+	if err != nil {
+		return err
+	} */
+	// err = os.WriteFile(filepath.Join(config.Home, config.File), b, os.ModePerm)
+	/* if err != nil {
+		return err
+	} */
+	return nil
+}
+
+// newModel returns the application model holding the given snippets organized
+// in their folders.
+func newModel(config Config, snippets []Snippet) *Model {
 	var folders = make(map[Folder][]list.Item)
 	var items []list.Item
 	for _, snippet := range snippets {
@@ -257,6 +285,15 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 
 	defaultStyles := DefaultStyles(config)
 
+	// Folders without any snippets are shown as well.
+	if entries, err := os.ReadDir(config.Root); err == nil {
+		for _, e := range entries {
+			if _, ok := folders[Folder(e.Name())]; !ok && e.IsDir() && !ignoredFile(e.Name()) {
+				folders[Folder(e.Name())] = []list.Item{}
+			}
+		}
+	}
+
 	var folderItems []list.Item
 	foldersSlice := maps.Keys(folders)
 	slices.Sort(foldersSlice)
@@ -273,7 +310,7 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 	folderList.SetFilteringEnabled(false)
 	folderList.SetShowStatusBar(false)
 	folderList.DisableQuitKeybindings()
-	folderList.Styles.NoItems = lipgloss.NewStyle().Margin(0, 2).Foreground(lipgloss.Color(config.GrayColor))
+	folderList.Styles.NoItems = lipgloss.NewStyle().Margin(0, 2).Foreground(lipgloss.Color(config.palette().Gray))
 	folderList.SetStatusBarItemName("folder", "folders")
 
 	content := viewport.New(80, 0)
@@ -283,6 +320,16 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 	for folder, items := range folders {
 		lists[folder] = newList(items, 20, defaultStyles.Snippets.Focused)
 	}
+	if len(lists) <= 0 {
+		lists[Folder(defaultSnippetFolder)] = newList([]list.Item{}, 20, defaultStyles.Snippets.Focused)
+	}
+
+	cb, err := newClipboard(config.Clipboard, config.StateDir)
+	if err != nil {
+		cb, _ = newClipboard(autoClipboard, config.StateDir)
+	}
+	layout, _ := readLayout(config.StateDir)
+	sorts, _ := readSorts(config.StateDir)
 
 	m := &Model{
 		Lists:        lists,
@@ -294,41 +341,33 @@ func runInteractiveMode(config Config, snippets []Snippet) error {
 		keys:         DefaultKeyMap,
 		help:         help.New(),
 		config:       config,
+		marked:       map[string]Snippet{},
+		diagnostics:  map[string][]diagnostic{},
+		editor:       newEditor(),
+		clipboard:    cb,
+		layout:       layout,
+		sorts:        sorts,
 		inputs: []textinput.Model{
 			newTextInput(defaultSnippetFolder + " "),
 			newTextInput(defaultSnippetName + " "),
 			newTextInput(config.DefaultLanguage),
+			newTextInput("folder"),
+			newTextInput("type to search actions, folders and snippets"),
+			newTextInput("search"),
+			newPasswordInput(),
 		},
+		wrap:       config.Preview.Wrap,
+		whitespace: config.Preview.Whitespace,
+		markdown:   config.Preview.Markdown,
 	}
-	p := tea.NewProgram(m, tea.WithAltScreen())
-	model, err := p.Run()
-	if err != nil {
-		return err
+	for folder := range lists {
+		m.sortList(folder)
 	}
-	fm, ok := model.(*Model)
-	if !ok {
-		return err
-	}
-	var allSnippets []list.Item
-	for _, list := range fm.Lists {
-		allSnippets = append(allSnippets, list.Items()...)
-	}
-	if len(allSnippets) <= 0 {
-		allSnippets = []list.Item{defaultSnippet}
-	}
-	/* b, err := json.Marshal(allSnippets)
-	if err != nil {
-		return err
-	} */
-	// err = os.WriteFile(filepath.Join(config.Home, config.File), b, os.ModePerm)
-	/* if err != nil {
-		return err
-	} */
-	return nil
+	return m
 }
 
 func newList(items []list.Item, height int, styles SnippetsBaseStyle) *list.Model {
-	snippetList := list.New(items, snippetDelegate{styles, navigatingState}, 25, height)
+	snippetList := list.New(items, snippetDelegate{styles, navigatingState, nil}, 25, height)
 	snippetList.SetShowHelp(false)
 	snippetList.SetShowFilter(false)
 	snippetList.SetShowTitle(false)
@@ -336,6 +375,9 @@ func newList(items []list.Item, height int, styles SnippetsBaseStyle) *list.Mode
 	snippetList.Styles.NoItems = lipgloss.NewStyle().Margin(0, 2).Foreground(lipgloss.Color("8")).MaxWidth(35 - 2)
 	snippetList.FilterInput.Prompt = "Find: "
 	snippetList.FilterInput.PromptStyle = styles.Title
+	if !blinkCursors {
+		snippetList.FilterInput.SetCursorMode(textinput.CursorStatic)
+	}
 	snippetList.SetStatusBarItemName("snippet", "snippets")
 	snippetList.DisableQuitKeybindings()
 	snippetList.Styles.Title = styles.Title
@@ -344,10 +386,26 @@ func newList(items []list.Item, height int, styles SnippetsBaseStyle) *list.Mode
 	return &snippetList
 }
 
+// blinkCursors is whether the cursors of inputs blink. It is a variable so
+// that tests can keep the cursors from blinking.
+var blinkCursors = true
+
 func newTextInput(placeholder string) textinput.Model {
 	i := textinput.New()
 	i.Prompt = ""
 	i.PromptStyle = lipgloss.NewStyle().Margin(0, 1)
 	i.Placeholder = placeholder
+	if !blinkCursors {
+		i.SetCursorMode(textinput.CursorStatic)
+	}
+	return i
+}
+
+// newPasswordInput returns the input that passphrases are entered in, which
+// masks them.
+func newPasswordInput() textinput.Model {
+	i := newTextInput("passphrase")
+	i.EchoMode = textinput.EchoPassword
+	i.EchoCharacter = '*'
 	return i
 }
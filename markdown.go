@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"golang.org/x/exp/slices"
+)
+
+// markdownLanguages are the languages of snippets that are Markdown
+// documents.
+var markdownLanguages = []string{"md", "markdown"}
+
+// isMarkdown reports whether the snippet is a Markdown document, which
+// Markdown snippets and the snippets in notes folders are.
+func (c Config) isMarkdown(s Snippet) bool {
+	return slices.Contains(markdownLanguages, strings.ToLower(s.Language)) || slices.Contains(c.Preview.Notes, s.Folder)
+}
+
+// renderMarkdown renders the Markdown document for the terminal, wrapping it
+// at the width. Fenced code blocks are highlighted as their language.
+func renderMarkdown(content string, width int, config Config) (string, error) {
+	style := "light"
+	switch {
+	case config.profile == termenv.Ascii:
+		style = "notty"
+	case lipgloss.HasDarkBackground():
+		style = "dark"
+	}
+	r, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(style),
+		glamour.WithColorProfile(config.profile),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		return "", err
+	}
+	s, err := r.Render(content)
+	if err != nil {
+		return "", err
+	}
+	return strings.Trim(s, "\n"), nil
+}
+
+// rendersMarkdown reports whether the previewed snippet is rendered as a
+// Markdown document rather than highlighted as code.
+func (m *Model) rendersMarkdown() bool {
+	return m.markdown && m.config.isMarkdown(m.previewed) && !m.masked()
+}
+
+// highlightContent highlights the contents of the previewed snippet, or
+// renders them if it is a Markdown document, and shows them in the content
+// pane. Sensitive snippets are masked until they are revealed.
+func (m *Model) highlightContent() error {
+	var (
+		s   string
+		err error
+	)
+	if m.masked() {
+		s = maskContent(m.source)
+	} else if m.rendersMarkdown() {
+		s, err = renderMarkdown(m.source, m.Code.Width, m.config)
+	} else {
+		s, err = highlightCode(m.source, m.previewed.Language, m.config)
+	}
+	if err != nil {
+		return err
+	}
+	m.highlighted = s
+	m.renderContent()
+	return nil
+}
+
+// toggleMarkdown switches between rendering Markdown snippets and showing
+// their source.
+func (m *Model) toggleMarkdown() {
+	m.markdown = !m.markdown
+	m.scrollX = 0
+	if m.highlighted == "" {
+		return
+	}
+	if err := m.highlightContent(); err != nil {
+		m.displayError("Unable to render file.")
+	}
+}
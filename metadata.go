@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// metadataFile is the name of the file in the snippet root that holds the
+// metadata of all snippets.
+const metadataFile = ".snp.yaml"
+
+// Metadata holds the information about a snippet that is not part of the
+// snippet file itself.
+type Metadata struct {
+	Tags        []string `yaml:"tags,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Sensitive   bool     `yaml:"sensitive,omitempty"`
+}
+
+// empty reports whether there is no metadata worth storing.
+func (md Metadata) empty() bool {
+	return len(md.Tags) <= 0 && md.Description == "" && !md.Sensitive
+}
+
+// Library maps the folder/file of every snippet to its metadata.
+type Library map[string]Metadata
+
+// metadataKey returns the key of the snippet file in the library.
+func metadataKey(folder, file string) string {
+	return folder + "/" + file
+}
+
+// readLibrary returns the metadata stored in the snippet root.
+// A missing metadata file results in an empty library.
+func readLibrary(root string) (Library, error) {
+	lib := Library{}
+	b, err := os.ReadFile(filepath.Join(root, metadataFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return lib, nil
+	}
+	if err != nil {
+		return lib, err
+	}
+	if err := yaml.Unmarshal(b, &lib); err != nil {
+		return Library{}, err
+	}
+	return lib, nil
+}
+
+// write stores the library in the snippet root, replacing the metadata file
+// at once so that a crash cannot leave it truncated.
+func (lib Library) write(root string) error {
+	b, err := yaml.Marshal(lib)
+	if err != nil {
+		return err
+	}
+	return replaceFile(filepath.Join(root, metadataFile), b, 0644)
+}
+
+// move moves the metadata of a snippet file to a new folder/file.
+func (lib Library) move(fromFolder, fromFile, toFolder, toFile string) {
+	from, to := metadataKey(fromFolder, fromFile), metadataKey(toFolder, toFile)
+	md, ok := lib[from]
+	if !ok {
+		// A snippet that is overwritten loses its metadata.
+		delete(lib, to)
+		return
+	}
+	delete(lib, from)
+	lib[to] = md
+}
+
+// moveFolder moves the metadata of all snippets in a folder to another
+// folder.
+func (lib Library) moveFolder(from, to string) {
+	if from == to {
+		return
+	}
+	for key, md := range lib {
+		if file := strings.TrimPrefix(key, from+"/"); file != key {
+			delete(lib, key)
+			lib[metadataKey(to, file)] = md
+		}
+	}
+}
+
+// removeFolder removes the metadata of all snippets in a folder.
+func (lib Library) removeFolder(folder string) {
+	for key := range lib {
+		if strings.HasPrefix(key, folder+"/") {
+			delete(lib, key)
+		}
+	}
+}
+
+// updateLibrary applies the change to the library stored in the snippet root.
+func updateLibrary(root string, change func(Library)) error {
+	lib, err := readLibrary(root)
+	if err != nil {
+		return err
+	}
+	change(lib)
+	return lib.write(root)
+}
+
+// parseTags returns the tags in the string, separated by spaces or commas.
+func parseTags(s string) []string {
+	return strings.Fields(strings.ReplaceAll(s, ",", " "))
+}
+
+// addTags returns the tags with the new tags added, skipping duplicates.
+func addTags(tags []string, add []string) []string {
+	result := append([]string{}, tags...)
+	for _, t := range add {
+		if !slices.Contains(result, t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// removeTags returns the tags without the removed tags.
+func removeTags(tags []string, remove []string) []string {
+	var result []string
+	for _, t := range tags {
+		if !slices.Contains(remove, t) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// setTags stores the tags of the snippet in the library.
+func setTags(root string, s Snippet) error {
+	return updateLibrary(root, func(lib Library) {
+		key := metadataKey(s.Folder, s.File)
+		md := lib[key]
+		md.Tags = s.Tags
+		if md.empty() {
+			delete(lib, key)
+			return
+		}
+		lib[key] = md
+	})
+}
@@ -1,30 +1,33 @@
 package main
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/alecthomas/chroma/v2/quick"
-	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/fsnotify/fsnotify"
 	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
 )
 
 const maxPane = 3
 
+// tick returns a Cmd that sends the message after the duration. It is a
+// variable so that tests can keep timers from firing.
+var tick = tea.Tick
+
 type pane int
 
 const (
@@ -43,6 +46,25 @@ const (
 	pastingState
 	quittingState
 	editingState
+	creatingFolderState
+	renamingFolderState
+	mergingFolderState
+	deletingFolderState
+	movingState
+	retypingState
+	taggingState
+	untaggingState
+	exportingState
+	confirmingExportState
+	collidingState
+	inlineEditingState
+	pickingState
+	paletteState
+	contentSearchState
+	confirmingRunState
+	runArgsState
+	runningState
+	unlockingState
 )
 
 type input int
@@ -51,6 +73,10 @@ const (
 	folderInput input = iota
 	nameInput
 	languageInput
+	promptInput
+	paletteInput
+	searchInput
+	passphraseInput
 )
 
 // Model represents the state of the application.
@@ -62,10 +88,27 @@ type Model struct {
 	keys KeyMap
 	// the help model.
 	help help.Model
-	// the height of the terminal.
+	// the width of the terminal and the height that is left for the panes.
+	width  int
 	height int
+	// the layout of the panes.
+	layout Layout
+	// the sort modes of the folders.
+	sorts Sorts
+	// the last click on an item, to detect double clicks.
+	lastClick click
 	// the working directory.
 	Workdir string
+	// the watcher of the snippet files, nil if not watching.
+	watcher *fsnotify.Watcher
+	// the clipboard that snippets are copied to and pasted from.
+	clipboard Clipboard
+	// the recent clipboard captures and the one selected to paste.
+	history      History
+	historyIndex int
+	// the entries of the command palette and the one selected.
+	palette      []command
+	paletteIndex int
 	// the List of snippets to display to the user.
 	Lists map[Folder]*list.Model
 	// the list of Folders to display to the user.
@@ -73,6 +116,25 @@ type Model struct {
 	// the viewport of the Code snippet.
 	Code        viewport.Model
 	LineNumbers viewport.Model
+	// the snippet in the content pane and its contents, whether Markdown
+	// snippets are rendered, the highlighted contents, the line of each of
+	// its rows and the width of its longest line.
+	previewed    Snippet
+	source       string
+	markdown     bool
+	highlighted  string
+	rowLines     []int
+	contentWidth int
+	// whether long lines are wrapped, whitespace is shown and how far the
+	// content is scrolled horizontally.
+	wrap       bool
+	whitespace bool
+	scrollX    int
+	// the term that is searched for in the content pane, the rows that match
+	// it and the current match.
+	searchTerm string
+	matches    []int
+	matchIndex int
 	// the input for snippet folder, name, language
 	activeInput input
 	inputs      []textinput.Model
@@ -80,6 +142,33 @@ type Model struct {
 	pane pane
 	// the current state / action of the application.
 	state state
+	// the marked snippets by folder/file, which actions apply to.
+	marked map[string]Snippet
+	// the move that is waiting for the user to resolve a name collision.
+	pendingMove *pendingMove
+	// the export that is waiting for the user to decide on its encrypted
+	// snippets.
+	pendingExport *pendingExport
+	// the run of a snippet that is being confirmed or shows its output.
+	run *pendingRun
+	// the keyring of the encrypted snippets, nil while they are locked, and
+	// the unlocking that waits for the passphrase.
+	keyring   *keyring
+	unlocking *pendingUnlock
+	// whether the application clears sensitive snippets that were copied from
+	// the clipboard itself, as no snp process could be started to clear
+	// them, and whether the previewed sensitive snippet is revealed.
+	clearingClipboard bool
+	revealed          bool
+	// the problems that formatters and linters found by folder/file.
+	diagnostics map[string][]diagnostic
+	// the inline editor of the snippet contents, with the contents as they
+	// were when editing started, whether the contents are being previewed and
+	// whether discarding the changes is being confirmed.
+	editor           textarea.Model
+	editorOriginal   string
+	editorPreview    bool
+	editorDiscarding bool
 	// stying for components
 	ListStyle    SnippetsBaseStyle
 	FoldersStyle FoldersBaseStyle
@@ -94,9 +183,7 @@ func (m *Model) Init() tea.Cmd {
 	m.Folders.Styles.TitleBar = m.FoldersStyle.TitleBar
 	m.updateKeyMap()
 
-	return func() tea.Msg {
-		return updateContentMsg(m.selectedSnippet())
-	}
+	return tea.Batch(m.updateContent(), m.watch())
 }
 
 // updateContentMsg tells the application to update the content view with the
@@ -113,6 +200,13 @@ func (m *Model) updateContent() tea.Cmd {
 	}
 }
 
+// snippetEditedMsg tells the application that the snippet was edited in the
+// editor.
+type snippetEditedMsg Snippet
+
+// errorMsg tells the application to display the error of a command.
+type errorMsg struct{ err error }
+
 type updateFoldersMsg struct {
 	items               []list.Item
 	selectedFolderIndex int
@@ -141,15 +235,57 @@ func changeState(newState state) tea.Cmd {
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case updateFoldersMsg:
-		setItemsCmd := m.Folders.SetItems(msg.items)
-		m.Folders.Select(msg.selectedFolderIndex)
-		var cmd tea.Cmd
-		m.Folders, cmd = m.Folders.Update(msg)
-		return m, tea.Batch(setItemsCmd, cmd)
+		return m, m.setFolders(msg)
 	case updateContentMsg:
 		return m.updateContentView(msg)
+	case decryptedMsg:
+		// The selection may have moved on while the snippet was decrypted.
+		if s := m.selectedSnippet(); s.Folder != msg.snippet.Folder || s.File != msg.snippet.File {
+			return m, nil
+		}
+		m.showContent(msg.snippet, msg.content, msg.err)
+		return m, nil
+	case passphraseCheckedMsg:
+		return m, m.finishUnlocking(msg)
+	case errorMsg:
+		m.displayError(msg.err.Error())
+		return m, nil
+	case runFinishedMsg:
+		m.finishRun(msg)
+		return m, nil
+	case sensitiveCopiedMsg:
+		return m, tea.Batch(changeState(copyingState), m.clearClipboardAfter())
+	case clearClipboardMsg:
+		if err := m.clearClipboard(m.config.Sensitive.clearAfter()); err != nil {
+			m.displayError(err.Error())
+		}
+		return m, nil
+	case snippetEditedMsg:
+		return m, tea.Batch(m.checkEditedSnippet(Snippet(msg)), func() tea.Msg {
+			return updateContentMsg(msg)
+		})
+	case checkedMsg:
+		return m, m.setDiagnostics(msg)
+	case historyMsg:
+		m.history = History(msg)
+		if m.historyIndex >= len(m.history) {
+			m.historyIndex = 0
+		}
+		return m, nil
+	case snippetsAddedMsg:
+		return m, tea.Batch(m.addSnippets(msg), m.watch())
+	case snippetsRemovedMsg:
+		return m, tea.Batch(m.removeSnippets(msg), m.watch())
+	case folderRemovedMsg:
+		return m, tea.Batch(m.removeFolder(Folder(msg)), m.watch())
+	case snippetChangedMsg:
+		var cmd tea.Cmd
+		if s := m.selectedSnippet(); s.Folder == msg.Folder && s.File == msg.File {
+			cmd = m.updateContent()
+		}
+		return m, tea.Batch(cmd, m.watch())
 	case changeStateMsg:
-		m.List().SetDelegate(snippetDelegate{m.ListStyle, msg.newState})
+		m.List().SetDelegate(snippetDelegate{m.ListStyle, msg.newState, m.marked})
 
 		var cmd tea.Cmd
 
@@ -172,7 +308,6 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			if wasEditing {
 				m.blurInputs()
-				i := m.List().Index()
 				snippet := m.selectedSnippet()
 
 				var newName string
@@ -194,30 +329,16 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					newLanguage = m.config.DefaultLanguage
 				}
 
-				if newFolder != snippet.Folder {
-					_ = os.Rename(m.selectedSnippetFilePath(), filepath.Join(m.config.Root, snippet.Folder, snippet.File))
-					snippet.Folder = newFolder
-				}
-				if newLanguage != snippet.Language || newName != snippet.Name {
-					newFile := fmt.Sprintf("%s.%s", snippet.Name, newLanguage)
-					_ = os.Rename(m.selectedSnippetFilePath(), filepath.Join(m.config.Root, snippet.Folder, newFile))
-					snippet.File = newFile
-					snippet.Language = newLanguage
-				}
-				setCmd := m.List().SetItem(i, snippet)
 				m.pane = snippetPane
-				cmd = tea.Batch(setCmd, m.updateFolders(), m.updateContent())
+				cmd = m.moveSnippet(snippet, newFolder, snippet.fileName(newName, newLanguage), failOnCollision)
 			}
 		case pastingState:
-			content, err := clipboard.ReadAll()
-			if err != nil {
-				return m, changeState(navigatingState)
-			}
-			f, err := os.OpenFile(m.selectedSnippetFilePath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
-				return m, changeState(navigatingState)
+			if err := m.pasteClipboard(); err != nil {
+				m.state = navigatingState
+				m.updateKeyMap()
+				m.displayError(err.Error())
+				return m, nil
 			}
-			f.WriteString(content)
 			return m, changeState(navigatingState)
 		case deletingState:
 			m.state = deletingState
@@ -237,7 +358,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.pane = snippetPane
 			m.state = copyingState
 			m.updateActivePane(msg)
-			cmd = tea.Tick(time.Second, func(t time.Time) tea.Msg {
+			cmd = tick(time.Second, func(t time.Time) tea.Msg {
 				return changeStateMsg{navigatingState}
 			})
 		}
@@ -246,34 +367,94 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateActivePane(msg)
 		return m, cmd
 	case tea.WindowSizeMsg:
+		m.width = msg.Width
 		m.height = msg.Height - 4
-		for _, li := range m.Lists {
-			li.SetHeight(m.height)
-		}
-		m.Folders.SetHeight(m.height)
-		m.Code.Height = m.height
-		m.LineNumbers.Height = m.height
-		m.Code.Width = msg.Width - m.List().Width() - m.Folders.Width() - 20
-		m.LineNumbers.Width = 5
+		m.help.Width = msg.Width - marginStyle.GetHorizontalFrameSize()
+		m.resize()
+		m.updateStyles()
 		return m, nil
+	case tea.MouseMsg:
+		return m, m.updateMouse(msg)
 	case tea.KeyMsg:
 		if m.List().FilterState() == list.Filtering {
 			break
 		}
 
+		if m.state == inlineEditingState {
+			return m, m.updateInlineEdit(msg)
+		}
+
+		if m.state == pickingState {
+			return m, m.updatePicker(msg)
+		}
+
+		if m.state == paletteState {
+			return m, m.updatePalette(msg)
+		}
+
+		if m.state == contentSearchState {
+			return m, m.updateContentSearch(msg)
+		}
+
+		if m.isRunning() {
+			return m, m.updateRun(msg)
+		}
+
+		if m.state == unlockingState {
+			return m, m.updateUnlock(msg)
+		}
+
 		if m.state == deletingState {
 			switch {
 			case key.Matches(msg, m.keys.Confirm):
-				m.List().RemoveItem(m.List().Index())
+				cmd := m.deleteSnippets()
 				m.state = navigatingState
 				m.updateKeyMap()
-				return m, tea.Batch(changeState(navigatingState), func() tea.Msg {
+				return m, tea.Batch(cmd, changeState(navigatingState), func() tea.Msg {
 					return updateContentMsg(m.selectedSnippet())
 				})
 			case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
 				return m, changeState(navigatingState)
 			}
 			return m, nil
+		} else if m.state == collidingState {
+			switch {
+			case key.Matches(msg, m.keys.Overwrite):
+				return m, m.resolveCollision(overwriteOnCollision)
+			case key.Matches(msg, m.keys.AddSuffix):
+				return m, m.resolveCollision(suffixOnCollision)
+			case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
+				return m, m.resolveCollision(failOnCollision)
+			}
+			return m, nil
+		} else if m.state == confirmingExportState {
+			switch {
+			case key.Matches(msg, m.keys.Confirm):
+				return m, m.resolveExport(true)
+			case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
+				return m, m.resolveExport(false)
+			}
+			return m, nil
+		} else if m.state == deletingFolderState {
+			switch {
+			case key.Matches(msg, m.keys.Confirm):
+				return m, m.deleteFolder()
+			case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
+				m.state = navigatingState
+				m.updateKeyMap()
+			}
+			return m, nil
+		} else if m.isPrompting() {
+			switch msg.String() {
+			case "esc":
+				m.cancelPrompt()
+				return m, nil
+			case "enter":
+				return m, m.submitPrompt()
+			}
+			var cmd tea.Cmd
+			m.inputs[promptInput], cmd = m.inputs[promptInput].Update(msg)
+			return m, cmd
 		} else if m.state == copyingState {
 			return m, changeState(navigatingState)
 		} else if m.state == editingState {
@@ -289,62 +470,104 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Batch(cmds...)
 		}
 
+		if m.updatePreview(msg) {
+			return m, nil
+		}
+
 		switch {
 		case key.Matches(msg, m.keys.NextPane):
 			m.nextPane()
 		case key.Matches(msg, m.keys.PreviousPane):
 			m.previousPane()
 		case key.Matches(msg, m.keys.Quit):
-			m.state = quittingState
-			return m, tea.Quit
+			return m, m.quit()
 		case key.Matches(msg, m.keys.NewSnippet):
 			m.state = creatingState
 			return m, m.createNewSnippetFile()
 		case key.Matches(msg, m.keys.PasteSnippet):
-			return m, changeState(pastingState)
+			return m, m.unlockFor([]Snippet{m.selectedSnippet()}, func() tea.Cmd {
+				return changeState(pastingState)
+			})
+		case key.Matches(msg, m.keys.PasteHistory):
+			return m, m.startPicking()
+		case key.Matches(msg, m.keys.NextTheme):
+			return m, m.nextTheme()
+		case key.Matches(msg, m.keys.Palette):
+			return m, m.openPalette()
+		case key.Matches(msg, m.keys.SortSnippets):
+			return m, m.toggleSort()
 		case key.Matches(msg, m.keys.RenameSnippet):
 			m.activeInput = nameInput
 			return m, changeState(editingState)
+		case key.Matches(msg, m.keys.NewFolder):
+			return m, m.prompt(creatingFolderState, "")
+		case key.Matches(msg, m.keys.RenameFolder):
+			return m, m.prompt(renamingFolderState, string(m.selectedFolder()))
+		case key.Matches(msg, m.keys.MergeFolder):
+			return m, m.prompt(mergingFolderState, "")
+		case key.Matches(msg, m.keys.DeleteFolder):
+			m.state = deletingFolderState
+			m.updateKeyMap()
+			return m, nil
 		case key.Matches(msg, m.keys.ChangeFolder):
 			m.pane = snippetPane
 			cmd := m.updateActivePane(msg)
 			return m, cmd
 		case key.Matches(msg, m.keys.ToggleHelp):
 			m.help.ShowAll = !m.help.ShowAll
-
-			var newHeight int
-			if m.help.ShowAll {
-				newHeight = m.height - 4
-			} else {
-				newHeight = m.height
-			}
-			m.List().SetHeight(newHeight)
-			m.Folders.SetHeight(newHeight)
-			m.Code.Height = newHeight
-			m.LineNumbers.Height = newHeight
+			m.resize()
+		case key.Matches(msg, m.keys.GrowPane):
+			return m, m.resizePane(resizeStep)
+		case key.Matches(msg, m.keys.ShrinkPane):
+			return m, m.resizePane(-resizeStep)
+		case key.Matches(msg, m.keys.ZoomPane):
+			return m, m.toggleZoom()
 		case key.Matches(msg, m.keys.SetFolder):
+			if len(m.marked) > 0 {
+				return m, m.prompt(movingState, "")
+			}
 			m.activeInput = folderInput
 			return m, changeState(editingState)
 		case key.Matches(msg, m.keys.SetLanguage):
+			if len(m.marked) > 0 {
+				return m, m.prompt(retypingState, "")
+			}
 			m.activeInput = languageInput
 			return m, changeState(editingState)
+		case key.Matches(msg, m.keys.MarkSnippet):
+			m.toggleMark()
+		case key.Matches(msg, m.keys.ClearMarks):
+			m.clearMarks()
+		case key.Matches(msg, m.keys.AddTag):
+			return m, m.prompt(taggingState, "")
+		case key.Matches(msg, m.keys.RemoveTag):
+			return m, m.prompt(untaggingState, "")
+		case key.Matches(msg, m.keys.ExportSnippets):
+			return m, m.prompt(exportingState, "snp-export")
 		case key.Matches(msg, m.keys.CopySnippet):
-			return m, func() tea.Msg {
-				content, err := os.ReadFile(m.selectedSnippetFilePath())
-				if err != nil {
-					return changeStateMsg{navigatingState}
-				}
-				clipboard.WriteAll(string(content))
-				return changeStateMsg{copyingState}
-			}
+			return m, m.unlockFor(m.targets(), m.copySnippets)
+		case key.Matches(msg, m.keys.Run):
+			return m, m.unlockFor([]Snippet{m.selectedSnippet()}, m.confirmRun)
+		case key.Matches(msg, m.keys.Unlock):
+			return m, m.unlock(m.updateContent)
+		case key.Matches(msg, m.keys.Encrypt):
+			return m, m.toggleEncryption()
 		case key.Matches(msg, m.keys.DeleteSnippet):
 			m.pane = snippetPane
 			m.updateActivePane(msg)
 			m.List().Title = "Delete? (y/N)"
 			return m, changeState(deletingState)
 		case key.Matches(msg, m.keys.EditSnippet):
+			if m.useInlineEditor() {
+				return m, m.unlockFor([]Snippet{m.selectedSnippet()}, m.startInlineEdit)
+			}
 			return m, m.editSnippet()
+		case key.Matches(msg, m.keys.InlineEdit):
+			return m, m.unlockFor([]Snippet{m.selectedSnippet()}, m.startInlineEdit)
 		case key.Matches(msg, m.keys.Search):
+			if m.pane == contentPane {
+				return m, m.startContentSearch()
+			}
 			m.pane = snippetPane
 		}
 	}
@@ -368,6 +591,54 @@ func (m *Model) focusInput(i input) tea.Cmd {
 	return m.inputs[i].Focus()
 }
 
+// prompt enters the state and focuses the prompt input with the given value.
+func (m *Model) prompt(newState state, value string) tea.Cmd {
+	m.state = newState
+	m.inputs[promptInput].SetValue(value)
+	m.updateKeyMap()
+	return m.focusInput(promptInput)
+}
+
+// isPrompting reports whether the application is asking for a value in the
+// prompt input.
+func (m *Model) isPrompting() bool {
+	switch m.state {
+	case creatingFolderState, renamingFolderState, mergingFolderState:
+		return true
+	case movingState, retypingState, taggingState, untaggingState, exportingState:
+		return true
+	}
+	return false
+}
+
+// isRunning reports whether a run of a snippet is being confirmed, asks for
+// its placeholders or shows its output.
+func (m *Model) isRunning() bool {
+	return m.state == confirmingRunState || m.state == runArgsState || m.state == runningState
+}
+
+// submitPrompt performs the action of the current state with the value that
+// was entered in the prompt.
+func (m *Model) submitPrompt() tea.Cmd {
+	value := strings.TrimSpace(m.inputs[promptInput].Value())
+	action := m.state
+	m.state = navigatingState
+	m.blurInputs()
+	m.updateKeyMap()
+	switch action {
+	case creatingFolderState, renamingFolderState, mergingFolderState:
+		return m.submitFolderPrompt(action, value)
+	}
+	return m.submitBulkPrompt(action, value)
+}
+
+// cancelPrompt leaves the prompt without any changes.
+func (m *Model) cancelPrompt() {
+	m.state = navigatingState
+	m.blurInputs()
+	m.updateKeyMap()
+}
+
 // selectedSnippetFilePath returns the file path of the snippet that is
 // currently selected.
 func (m *Model) selectedSnippetFilePath() string {
@@ -388,17 +659,39 @@ func (m *Model) previousPane() {
 }
 
 // editSnippet opens the editor with the selected snippet file path.
+//
+// Graphical editors are run in the background while the application stays
+// open, terminal editors take over the terminal until they exit. Encrypted
+// snippets are edited as a decrypted copy.
 func (m *Model) editSnippet() tea.Cmd {
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		editor = "vim"
+	s := m.selectedSnippet()
+	if s.Encrypted {
+		return m.unlock(m.editEncryptedSnippet)
 	}
-	cmd := exec.Command(editor, m.selectedSnippetFilePath())
-	return tea.ExecProcess(cmd, func(err error) tea.Msg {
-		return updateContentMsg(m.selectedSnippet())
+	_ = recordUse(m.config.StateDir, s)
+	return m.openEditor(s, m.selectedSnippetFilePath(), func(err error) error {
+		return err
 	})
 }
 
+// openEditor opens the editor with the file of the snippet. Once the editor
+// exits, exited is called with its error and returns the error to display.
+func (m *Model) openEditor(s Snippet, path string, exited func(error) error) tea.Cmd {
+	cmd, gui := editorCommand(m.config.Editor, s.Language, path, m.editLine())
+	done := func(err error) tea.Msg {
+		if err := exited(err); err != nil {
+			return errorMsg{err}
+		}
+		return snippetEditedMsg(s)
+	}
+	if gui {
+		return func() tea.Msg {
+			return done(cmd.Run())
+		}
+	}
+	return tea.ExecProcess(cmd, done)
+}
+
 func (m *Model) noContentHints() []keyHint {
 	return []keyHint{
 		{m.keys.EditSnippet, "edit contents"},
@@ -409,9 +702,18 @@ func (m *Model) noContentHints() []keyHint {
 	}
 }
 
+// setFolders replaces the items of the folders list.
+func (m *Model) setFolders(msg updateFoldersMsg) tea.Cmd {
+	setItemsCmd := m.Folders.SetItems(msg.items)
+	m.Folders.Select(msg.selectedFolderIndex)
+	var cmd tea.Cmd
+	m.Folders, cmd = m.Folders.Update(msg)
+	return tea.Batch(setItemsCmd, cmd)
+}
+
 // updateFolderView updates the folders list to display the current folders.
-func (m *Model) updateFoldersView() tea.Msg {
-	var selectedFolder Folder
+func (m *Model) updateFoldersView() updateFoldersMsg {
+	selectedFolder := m.selectedFolder()
 	selectedFolderIndex := m.Folders.Index()
 	for folder, li := range m.Lists {
 		for i, item := range li.Items() {
@@ -442,6 +744,9 @@ func (m *Model) updateFoldersView() tea.Msg {
 			selectedFolderIndex = i
 		}
 	}
+	if selectedFolderIndex >= len(folderItems) {
+		selectedFolderIndex = len(folderItems) - 1
+	}
 
 	return updateFoldersMsg{
 		items:               folderItems,
@@ -459,29 +764,48 @@ func (m *Model) updateContentView(msg updateContentMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	var b bytes.Buffer
-	content, err := os.ReadFile(filepath.Join(m.config.Root, msg.Folder, msg.File))
+	if m.isLocked(Snippet(msg)) {
+		m.previewed = Snippet(msg)
+		m.displayKeyHint(m.lockedHints())
+		m.updateKeyMap()
+		return m, nil
+	}
+
+	if msg.Encrypted {
+		return m, m.decryptSnippet(Snippet(msg))
+	}
+	content, err := m.readSnippet(Snippet(msg))
+	m.showContent(Snippet(msg), content, err)
+	return m, nil
+}
+
+// showContent shows the contents of the snippet in the content view, or a
+// hint if they could not be read.
+func (m *Model) showContent(s Snippet, content string, err error) {
+	if err != nil && s.Encrypted && !errors.Is(err, os.ErrNotExist) {
+		m.displayError(err.Error())
+		return
+	}
 	if err != nil {
 		m.displayKeyHint(m.noContentHints())
-		return m, nil
+		return
 	}
 
-	if string(content) == "" {
+	if content == "" {
 		m.displayKeyHint(m.noContentHints())
-		return m, nil
+		return
 	}
 
-	b.WriteString(string(content))
-	err = quick.Highlight(&b, string(content), msg.Language, "terminal16m", m.config.Theme)
-	if err != nil {
+	if s.Folder != m.previewed.Folder || s.File != m.previewed.File {
+		m.revealed = false
+	}
+	m.previewed = s
+	m.source = content
+	m.scrollX = 0
+	if err := m.highlightContent(); err != nil {
 		m.displayError("Unable to highlight file.")
-		return m, nil
 	}
-
-	s := b.String()
-	m.writeLineNumbers(lipgloss.Height(s))
-	m.Code.SetContent(s)
-	return m, nil
+	m.updateKeyMap()
 }
 
 type keyHint struct {
@@ -492,6 +816,7 @@ type keyHint struct {
 // displayKeyHint updates the content viewport with instructions on the
 // relevent key binding that the user should most likely press.
 func (m *Model) displayKeyHint(hints []keyHint) {
+	m.highlighted = ""
 	m.LineNumbers.SetContent(strings.Repeat("  ~ \n", len(hints)))
 	var s strings.Builder
 	for _, hint := range hints {
@@ -506,57 +831,69 @@ func (m *Model) displayKeyHint(hints []keyHint) {
 
 // displayError updates the content viewport with the error message provided.
 func (m *Model) displayError(error string) {
+	m.highlighted = ""
 	m.LineNumbers.SetContent(" ~ ")
 	m.Code.SetContent(fmt.Sprintf("%s",
 		m.ContentStyle.EmptyHint.Render(error),
 	))
 }
 
-// writeLineNumbers writes the number of line numbers to the line number
-// viewport.
-func (m *Model) writeLineNumbers(n int) {
-	var lineNumbers strings.Builder
-	for i := 1; i < n; i++ {
-		lineNumbers.WriteString(fmt.Sprintf("%3d \n", i))
-	}
-	m.LineNumbers.SetContent(lineNumbers.String() + "  ~ \n")
-}
-
-const tabSpaces = 4
-
 // updateActivePane updates the currently active pane.
 func (m *Model) updateActivePane(msg tea.Msg) tea.Cmd {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
+	m.resize()
+	m.updateStyles()
 	switch m.pane {
 	case folderPane:
-		m.ListStyle = DefaultStyles(m.config).Snippets.Blurred
-		m.ContentStyle = DefaultStyles(m.config).Content.Blurred
-		m.FoldersStyle = DefaultStyles(m.config).Folders.Focused
 		m.Folders, cmd = m.Folders.Update(msg)
 		m.updateKeyMap()
 		cmds = append(cmds, cmd, m.updateContent())
 	case snippetPane:
-		m.ListStyle = DefaultStyles(m.config).Snippets.Focused
-		m.ContentStyle = DefaultStyles(m.config).Content.Blurred
-		m.FoldersStyle = DefaultStyles(m.config).Folders.Blurred
 		*m.List(), cmd = (*m.List()).Update(msg)
 		cmds = append(cmds, cmd)
 	case contentPane:
-		m.ListStyle = DefaultStyles(m.config).Snippets.Blurred
-		m.ContentStyle = DefaultStyles(m.config).Content.Focused
-		m.FoldersStyle = DefaultStyles(m.config).Folders.Blurred
 		m.Code, cmd = m.Code.Update(msg)
 		cmds = append(cmds, cmd)
 		m.LineNumbers, cmd = m.LineNumbers.Update(msg)
 		cmds = append(cmds, cmd)
 	}
-	m.List().SetDelegate(snippetDelegate{m.ListStyle, m.state})
+
+	return tea.Batch(cmds...)
+}
+
+// updateStyles styles and sizes the panes according to the active pane.
+func (m *Model) updateStyles() {
+	g := m.geometry()
+	styles := DefaultStyles(m.config).resize(g.folders, g.snippets)
+	switch m.pane {
+	case folderPane:
+		m.ListStyle = styles.Snippets.Blurred
+		m.ContentStyle = styles.Content.Blurred
+		m.FoldersStyle = styles.Folders.Focused
+	case snippetPane:
+		m.ListStyle = styles.Snippets.Focused
+		m.ContentStyle = styles.Content.Blurred
+		m.FoldersStyle = styles.Folders.Blurred
+	case contentPane:
+		m.ListStyle = styles.Snippets.Blurred
+		m.ContentStyle = styles.Content.Focused
+		m.FoldersStyle = styles.Folders.Blurred
+	}
+	m.List().SetDelegate(snippetDelegate{m.ListStyle, m.state, m.marked})
 	m.Folders.SetDelegate(folderDelegate{m.FoldersStyle})
 	m.Folders.Styles.TitleBar = m.FoldersStyle.TitleBar
 	m.Folders.Styles.Title = m.FoldersStyle.Title
+}
 
-	return tea.Batch(cmds...)
+// quit quits the application. Sensitive snippets that the application would
+// clear from the clipboard later are cleared right away.
+func (m *Model) quit() tea.Cmd {
+	m.state = quittingState
+	if m.clearingClipboard {
+		_ = m.clearClipboard(0)
+	}
+	return tea.Quit
 }
 
 // updateKeyMap disables or enables the keys based on the current state of the
@@ -564,13 +901,64 @@ func (m *Model) updateActivePane(msg tea.Msg) tea.Cmd {
 func (m *Model) updateKeyMap() {
 	hasItems := len(m.List().VisibleItems()) > 0
 	isFiltering := m.List().FilterState() == list.Filtering
-	isEditing := m.state == editingState
-	m.keys.DeleteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
+	isEditing := m.state == editingState || m.state == inlineEditingState || m.state == pickingState || m.state == paletteState || m.state == contentSearchState || m.state == unlockingState || m.isRunning() || m.isPrompting()
+	inFolders := m.pane == folderPane
+	inContent := m.pane == contentPane
+	isSearching := inContent && m.searchTerm != "" && m.highlighted != "" && !isEditing
+	m.keys.DeleteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inFolders)
 	m.keys.CopySnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
 	m.keys.PasteSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
 	m.keys.EditSnippet.SetEnabled(hasItems && !isFiltering && !isEditing)
-	m.keys.NewSnippet.SetEnabled(!isFiltering && !isEditing)
-	m.keys.ChangeFolder.SetEnabled(m.pane == folderPane)
+	m.keys.NewSnippet.SetEnabled(!isFiltering && !isEditing && !inFolders && !isSearching)
+	m.keys.RenameSnippet.SetEnabled(!isEditing && !inFolders)
+	m.keys.ChangeFolder.SetEnabled(inFolders)
+	m.keys.NewFolder.SetEnabled(inFolders && !isEditing)
+	m.keys.RenameFolder.SetEnabled(inFolders && !isEditing)
+	m.keys.MergeFolder.SetEnabled(inFolders && !isEditing && len(m.Lists) > 1)
+	m.keys.DeleteFolder.SetEnabled(inFolders && !isEditing)
+	m.keys.MarkSnippet.SetEnabled(hasItems && !isFiltering && !isEditing && !inFolders)
+	m.keys.ClearMarks.SetEnabled(len(m.marked) > 0 && !isEditing)
+	m.keys.AddTag.SetEnabled(hasItems && !isFiltering && !isEditing)
+	m.keys.RemoveTag.SetEnabled(hasItems && !isFiltering && !isEditing)
+	m.keys.ExportSnippets.SetEnabled(hasItems && !isFiltering && !isEditing)
+	m.keys.InlineEdit.SetEnabled(hasItems && !isFiltering && !isEditing)
+	m.keys.SaveEdit.SetEnabled(m.state == inlineEditingState)
+	m.keys.DiscardEdit.SetEnabled(m.state == inlineEditingState)
+	m.keys.TogglePreview.SetEnabled(m.state == inlineEditingState)
+	m.keys.PasteHistory.SetEnabled(!isFiltering && !isEditing && !inFolders)
+	m.keys.NextTheme.SetEnabled(!isFiltering && !isEditing)
+	m.keys.PasteAppend.SetEnabled(m.state == pickingState)
+	m.keys.PasteReplace.SetEnabled(m.state == pickingState)
+	m.keys.PasteNew.SetEnabled(m.state == pickingState)
+	m.keys.GrowPane.SetEnabled(!isFiltering && !isEditing)
+	m.keys.ShrinkPane.SetEnabled(!isFiltering && !isEditing)
+	m.keys.ZoomPane.SetEnabled(!isFiltering && !isEditing)
+	m.keys.Palette.SetEnabled(!isFiltering && !isEditing)
+	m.keys.SortSnippets.SetEnabled(!isFiltering && !isEditing)
+	m.keys.RunCommand.SetEnabled(m.state == paletteState)
+	m.keys.ClosePalette.SetEnabled(m.state == paletteState)
+	m.keys.NextMatch.SetEnabled(isSearching)
+	m.keys.PreviousMatch.SetEnabled(isSearching)
+	m.keys.ScrollLeft.SetEnabled(inContent && !isEditing && !m.wrap)
+	m.keys.ScrollRight.SetEnabled(inContent && !isEditing && !m.wrap)
+	m.keys.ToggleWrap.SetEnabled(!isFiltering && !isEditing)
+	m.keys.ToggleSpaces.SetEnabled(!isFiltering && !isEditing)
+	m.keys.Run.SetEnabled(hasItems && !isFiltering && !isEditing)
+	m.keys.CloseOutput.SetEnabled(m.state == runningState)
+	m.keys.Reveal.SetEnabled(!isFiltering && !isEditing && m.highlighted != "" && m.config.isSensitive(m.previewed))
+	if m.revealed {
+		m.keys.Reveal.SetHelp("v", "hide")
+	} else {
+		m.keys.Reveal.SetHelp("v", "reveal")
+	}
+	m.keys.Unlock.SetEnabled(hasItems && !isFiltering && !isEditing && m.isLocked(m.selectedSnippet()))
+	m.keys.Encrypt.SetEnabled(hasItems && !isFiltering && !isEditing && !inFolders)
+	if m.selectedSnippet().Encrypted {
+		m.keys.Encrypt.SetHelp("ctrl+e", "decrypt")
+	} else {
+		m.keys.Encrypt.SetHelp("ctrl+e", "encrypt")
+	}
+	m.keys.ToggleMarkdown.SetEnabled(!isFiltering && !isEditing && m.highlighted != "" && m.config.isMarkdown(m.previewed))
 }
 
 // selectedSnippet returns the currently selected snippet.
@@ -605,26 +993,39 @@ func (m *Model) List() *list.Model {
 // createNewSnippet creates a new snippet file and adds it to the the list.
 func (m *Model) createNewSnippetFile() tea.Cmd {
 	return func() tea.Msg {
-		folder := defaultSnippetFolder
-		folderItem := m.Folders.SelectedItem()
-		if folderItem != nil && folderItem.FilterValue() != "" {
-			folder = folderItem.FilterValue()
-		}
+		_ = m.newSnippetFile("")
+		return changeStateMsg{navigatingState}
+	}
+}
+
+// newSnippetFile creates a snippet file with the content in the selected
+// folder and adds it to the list, selecting it.
+func (m *Model) newSnippetFile(content string) error {
+	folder := defaultSnippetFolder
+	folderItem := m.Folders.SelectedItem()
+	if folderItem != nil && folderItem.FilterValue() != "" {
+		folder = folderItem.FilterValue()
+	}
 
-		name := fmt.Sprintf("snippet-%d", rand.Intn(1000000))
-		file := fmt.Sprintf("%s.%s", name, m.config.DefaultLanguage)
-		_, _ = os.Create(filepath.Join(m.config.Root, folder, file))
+	name := fmt.Sprintf("snippet-%d", rand.Intn(1000000))
+	file := fmt.Sprintf("%s.%s", name, m.config.DefaultLanguage)
+	err := os.WriteFile(filepath.Join(m.config.Root, folder, file), []byte(content), 0644)
+	if err != nil {
+		return err
+	}
 
-		newSnippet := Snippet{
-			Name:     name,
-			File:     file,
-			Language: m.config.DefaultLanguage,
-			Folder:   folder,
-		}
+	newSnippet := Snippet{
+		Name:     name,
+		File:     file,
+		Language: m.config.DefaultLanguage,
+		Folder:   folder,
+	}
 
+	if indexOfSnippet(m.List(), newSnippet) < 0 {
 		m.List().InsertItem(m.List().Index(), newSnippet)
-		return changeStateMsg{navigatingState}
+		m.sortList(Folder(folder))
 	}
+	return nil
 }
 
 // View returns the view string for the application model.
@@ -637,41 +1038,128 @@ func (m *Model) View() string {
 		folder   = m.ContentStyle.Title.Render(m.selectedSnippet().Folder)
 		name     = m.ContentStyle.Title.Render(m.selectedSnippet().Name)
 		language = m.ContentStyle.Title.Render(m.selectedSnippet().Language)
+		tags     = m.ContentStyle.Separator.Render(tagsString(m.selectedSnippet().Tags))
 		titleBar = m.ListStyle.TitleBar.Render("Snippets")
+		content  = lipgloss.JoinHorizontal(lipgloss.Left,
+			m.ContentStyle.LineNumber.Render(m.LineNumbers.View()),
+			m.ContentStyle.Base.Render(m.Code.View()),
+		)
 	)
 
+	if m.state == inlineEditingState {
+		content = m.ContentStyle.Base.Render(m.editorView())
+		if m.editorPreview {
+			tags = m.ContentStyle.Separator.Render("(preview)")
+		}
+		if m.editorDirty() {
+			tags = lipgloss.JoinHorizontal(lipgloss.Left, tags, m.ContentStyle.Separator.Render("[+]"))
+		}
+	}
+
+	folders := m.Folders
+	switch m.state {
+	case creatingFolderState:
+		folders.Title = "New: " + m.inputs[promptInput].View()
+	case renamingFolderState:
+		folders.Title = "Rename: " + m.inputs[promptInput].View()
+	case mergingFolderState:
+		folders.Title = "Merge into: " + m.inputs[promptInput].View()
+	case deletingFolderState:
+		folders.Title = "Delete? (y/N)"
+		folders.Styles.TitleBar = m.FoldersStyle.DeletedTitleBar
+	}
+
 	if m.state == editingState {
 		folder = m.inputs[folderInput].View()
 		name = m.inputs[nameInput].View()
 		language = m.inputs[languageInput].View()
+	} else if m.state == copyingState && len(m.marked) > 0 {
+		titleBar = m.ListStyle.CopiedTitleBar.Render(fmt.Sprintf("Copied %d Snippets!", len(m.marked)))
 	} else if m.state == copyingState {
 		titleBar = m.ListStyle.CopiedTitleBar.Render("Copied Snippet!")
+	} else if m.state == deletingState && len(m.marked) > 0 {
+		titleBar = m.ListStyle.DeletedTitleBar.Render(fmt.Sprintf("Delete %d Snippets? (y/N)", len(m.marked)))
 	} else if m.state == deletingState {
 		titleBar = m.ListStyle.DeletedTitleBar.Render("Delete Snippet? (y/N)")
+	} else if m.state == collidingState {
+		titleBar = m.ListStyle.DeletedTitleBar.Render("Exists! o: overwrite s: suffix")
+	} else if m.state == confirmingExportState {
+		titleBar = m.ListStyle.TitleBar.Render(fmt.Sprintf("Export %d encrypted too? (y/N)", m.pendingExport.encrypted))
+	} else if m.state == pickingState {
+		titleBar = m.ListStyle.CopiedTitleBar.Render("a: append r: replace n: new")
+	} else if m.state == confirmingRunState {
+		titleBar = m.ListStyle.CopiedTitleBar.Render(fmt.Sprintf("Run %s? (y/N)", m.run.snippet.Name))
+	} else if m.state == unlockingState {
+		titleBar = m.ListStyle.TitleBar.Render(m.unlockLabel() + m.inputs[passphraseInput].View())
+	} else if m.editorDiscarding {
+		titleBar = m.ListStyle.DeletedTitleBar.Render("Discard changes? (y/N)")
+	} else if m.state == runArgsState {
+		titleBar = m.ListStyle.TitleBar.Render(m.run.values[m.run.asked].name + ": " + m.inputs[promptInput].View())
+	} else if label, ok := bulkPromptLabels[m.state]; ok {
+		titleBar = m.ListStyle.TitleBar.Render(label + m.inputs[promptInput].View())
+	} else if len(m.marked) > 0 {
+		titleBar = m.ListStyle.TitleBar.Render(fmt.Sprintf("Snippets (%d marked)", len(m.marked)))
 	} else if m.List().SettingFilter() {
 		titleBar = m.ListStyle.TitleBar.Render(m.List().FilterInput.View())
+	} else if mode := m.sorts.mode(string(m.selectedFolder())); mode != nameSort {
+		titleBar = m.ListStyle.TitleBar.Render("Snippets by " + string(mode))
+	}
+
+	header := lipgloss.JoinHorizontal(lipgloss.Left,
+		folder,
+		m.ContentStyle.Separator.Render("/"),
+		name,
+		m.ContentStyle.Separator.Render("."),
+		language,
+		tags,
+	)
+	if m.state == pickingState {
+		header = m.ContentStyle.Title.Render("Clipboard History")
+		content = m.ContentStyle.Base.Render(m.historyView())
+	} else if m.state == paletteState {
+		header = lipgloss.JoinHorizontal(lipgloss.Left,
+			m.ContentStyle.Title.Render("Command"),
+			m.ContentStyle.Separator.Render(m.inputs[paletteInput].View()),
+		)
+		content = m.ContentStyle.Base.Render(m.paletteView())
+	} else if m.state == runningState {
+		header = lipgloss.JoinHorizontal(lipgloss.Left,
+			m.ContentStyle.Title.Render("Output"),
+			m.ContentStyle.Separator.Render(m.run.snippet.String()+" "+m.runStatus()),
+		)
+	} else if m.state == contentSearchState {
+		header = lipgloss.JoinHorizontal(lipgloss.Left,
+			m.ContentStyle.Title.Render("Find"),
+			m.ContentStyle.Separator.Render(m.inputs[searchInput].View()),
+		)
+	} else if m.masked() && m.highlighted != "" {
+		header = lipgloss.JoinHorizontal(lipgloss.Left, header, m.ContentStyle.Separator.Render("(hidden)"))
+	} else if m.searchTerm != "" && m.highlighted != "" {
+		header = lipgloss.JoinHorizontal(lipgloss.Left, header, m.ContentStyle.Separator.Render(m.searchStatus()))
+	} else if n := len(m.previewedDiagnostics()); n > 0 && m.highlighted != "" {
+		header = lipgloss.JoinHorizontal(lipgloss.Left, header, m.ContentStyle.Diagnostic.Render(" "+problemsString(n)))
 	}
 
+	var panes []string
+	g := m.geometry()
+	if g.folders > 0 {
+		panes = append(panes, m.FoldersStyle.Base.Render(folders.View()))
+	}
+	if g.snippets > 0 {
+		panes = append(panes, m.ListStyle.Base.Render(titleBar+m.List().View()))
+	}
+	panes = append(panes, lipgloss.JoinVertical(lipgloss.Top,
+		header,
+		content,
+	))
+
+	body := lipgloss.JoinHorizontal(lipgloss.Left, panes...)
+	if g.stacked {
+		body = lipgloss.JoinVertical(lipgloss.Left, panes...)
+	}
 	return lipgloss.JoinVertical(
 		lipgloss.Top,
-		lipgloss.JoinHorizontal(
-			lipgloss.Left,
-			m.FoldersStyle.Base.Render(m.Folders.View()),
-			m.ListStyle.Base.Render(titleBar+m.List().View()),
-			lipgloss.JoinVertical(lipgloss.Top,
-				lipgloss.JoinHorizontal(lipgloss.Left,
-					folder,
-					m.ContentStyle.Separator.Render("/"),
-					name,
-					m.ContentStyle.Separator.Render("."),
-					language,
-				),
-				lipgloss.JoinHorizontal(lipgloss.Left,
-					m.ContentStyle.LineNumber.Render(m.LineNumbers.View()),
-					m.ContentStyle.Base.Render(strings.ReplaceAll(m.Code.View(), "\t", strings.Repeat(" ", tabSpaces))),
-				),
-			),
-		),
+		body,
 		marginStyle.Render(m.help.View(m.keys)),
 	)
 }
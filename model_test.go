@@ -0,0 +1,387 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	agecrypt "filippo.io/age"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// ansiPattern matches the terminal escape sequences emitted by lipgloss and
+// chroma so that golden files only contain the visible layout.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// maxMessages guards against commands that keep producing messages forever.
+const maxMessages = 100
+
+// cmdTimeout is how long a command may run before the test fails.
+const cmdTimeout = 10 * time.Second
+
+func init() {
+	// Timers, such as the one leaving the copying state, never fire and
+	// cursors do not blink in tests so that the views do not depend on timing.
+	tick = func(time.Duration, func(time.Time) tea.Msg) tea.Cmd { return nil }
+	blinkCursors = false
+	// The test binary is not snp, so the application clears the clipboard
+	// itself.
+	startClipboardClearer = func(time.Duration) error { return errors.New("not snp") }
+}
+
+var (
+	keyTab      = tea.KeyMsg{Type: tea.KeyTab}
+	keyShiftTab = tea.KeyMsg{Type: tea.KeyShiftTab}
+	keyDown     = tea.KeyMsg{Type: tea.KeyDown}
+	keyEnter    = tea.KeyMsg{Type: tea.KeyEnter}
+	keyEsc      = tea.KeyMsg{Type: tea.KeyEsc}
+	keySpace    = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	keyCtrlU    = tea.KeyMsg{Type: tea.KeyCtrlU}
+	keyCtrlR    = tea.KeyMsg{Type: tea.KeyCtrlR}
+	keyCtrlS    = tea.KeyMsg{Type: tea.KeyCtrlS}
+)
+
+// keyRunes returns the key message for typing the given string.
+func keyRunes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// testSnippets writes a small snippet library, along with any extra files, to
+// a temporary root and returns the config pointing at it along with the
+// snippets read from it.
+func testSnippets(t *testing.T, extra map[string]string) (Config, []Snippet) {
+	t.Helper()
+
+	config := newConfig()
+	config.Root = t.TempDir()
+	config.StateDir = t.TempDir()
+	config.Clipboard = fileClipboard
+
+	files := map[string]string{
+		"misc/hello.go":    "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"misc/empty.txt":   "",
+		"shell/list.sh":    "ls -la\n",
+		"notes/readme.txt": "remember the milk\n",
+	}
+	for name, content := range extra {
+		files[name] = content
+	}
+	for name, content := range files {
+		path := filepath.Join(config.Root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return config, readSnippets(config)
+}
+
+// runCmd runs the command and returns its message, failing the test if the
+// command takes longer than cmdTimeout.
+func runCmd(t *testing.T, cmd tea.Cmd) tea.Msg {
+	t.Helper()
+
+	c := make(chan tea.Msg, 1)
+	go func() { c <- cmd() }()
+	select {
+	case msg := <-c:
+		return msg
+	case <-time.After(cmdTimeout):
+		t.Fatalf("command did not finish within %s", cmdTimeout)
+		return nil
+	}
+}
+
+// send delivers the messages to the model one by one, running every command
+// returned by the model and feeding the resulting messages back until the
+// model settles.
+func send(t *testing.T, m *Model, msgs ...tea.Msg) {
+	t.Helper()
+
+	var queue []tea.Msg
+	processed := 0
+	for _, msg := range msgs {
+		queue = append(queue, msg)
+		for len(queue) > 0 {
+			if processed++; processed > maxMessages {
+				t.Fatalf("model did not settle after %d messages", maxMessages)
+			}
+			msg := queue[0]
+			queue = queue[1:]
+
+			var cmds []tea.Cmd
+			if batch, ok := msg.(tea.BatchMsg); ok {
+				cmds = batch
+			} else {
+				_, cmd := m.Update(msg)
+				cmds = []tea.Cmd{cmd}
+			}
+
+			for _, cmd := range cmds {
+				if cmd == nil {
+					continue
+				}
+				if msg := runCmd(t, cmd); msg != nil {
+					queue = append(queue, msg)
+				}
+			}
+		}
+	}
+}
+
+// normalizeView strips escape sequences and trailing whitespace from the
+// rendered view.
+func normalizeView(view string) string {
+	lines := strings.Split(ansiPattern.ReplaceAllString(view, ""), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// assertGolden compares the view of the model with the golden file of the
+// test, rewriting the golden file when the -update flag is passed.
+func assertGolden(t *testing.T, m *Model) {
+	t.Helper()
+
+	got := normalizeView(m.View())
+	golden := filepath.Join("testdata", strings.ReplaceAll(t.Name(), "/", "_")+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("unable to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("view does not match %s\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+	}
+}
+
+func TestView(t *testing.T) {
+	tests := []struct {
+		name  string
+		msgs  []tea.Msg
+		files map[string]string
+	}{
+		{"initial", nil, nil},
+		{"next snippet", []tea.Msg{keyDown}, nil},
+		{"empty snippet", []tea.Msg{keyDown, keyDown}, nil},
+		{"content pane", []tea.Msg{keyTab}, nil},
+		{"folder pane", []tea.Msg{keyShiftTab}, nil},
+		{"change folder", []tea.Msg{keyShiftTab, keyDown, keyEnter}, nil},
+		{"help", []tea.Msg{keyRunes("?")}, nil},
+		{"rename", []tea.Msg{keyRunes("r")}, nil},
+		{"rename typing", []tea.Msg{keyRunes("r"), keyRunes("x")}, nil},
+		{"set language", []tea.Msg{keyRunes("L")}, nil},
+		{"rename cancel", []tea.Msg{keyRunes("r"), keyEsc}, nil},
+		{"delete", []tea.Msg{keyRunes("x")}, nil},
+		{"delete cancel", []tea.Msg{keyRunes("x"), keyRunes("N")}, nil},
+		{"delete confirm", []tea.Msg{keyRunes("x"), keyRunes("y")}, nil},
+		{"copy", []tea.Msg{changeStateMsg{copyingState}}, nil},
+		{"copy dismiss", []tea.Msg{changeStateMsg{copyingState}, keyDown}, nil},
+		{"snippet added", []tea.Msg{snippetsAddedMsg{newSnippet("misc", "added.txt"), newSnippet("docker", "run.sh")}}, nil},
+		{"snippet removed", []tea.Msg{snippetsRemovedMsg{newSnippet("misc", "empty.txt")}}, nil},
+		{"new folder", []tea.Msg{keyShiftTab, keyRunes("n"), keyRunes("go")}, nil},
+		{"new folder submit", []tea.Msg{keyShiftTab, keyRunes("n"), keyRunes("go"), keyEnter}, nil},
+		{"rename folder", []tea.Msg{keyShiftTab, keyRunes("r"), keyRunes("s"), keyEnter}, nil},
+		{"merge folder", []tea.Msg{keyShiftTab, keyRunes("m"), keyRunes("shell"), keyEnter}, nil},
+		{"delete folder", []tea.Msg{keyShiftTab, keyRunes("x")}, nil},
+		{"delete folder confirm", []tea.Msg{keyShiftTab, keyRunes("x"), keyRunes("y")}, nil},
+		{"mark", []tea.Msg{keySpace}, nil},
+		{"mark all", []tea.Msg{keySpace, keySpace}, nil},
+		{"mark clear", []tea.Msg{keySpace, keySpace, keyRunes("U")}, nil},
+		{"mark delete", []tea.Msg{keySpace, keySpace, keyRunes("x")}, nil},
+		{"mark move", []tea.Msg{keySpace, keySpace, keyRunes("R"), keyRunes("shell")}, nil},
+		{"mark move submit", []tea.Msg{keySpace, keySpace, keyRunes("R"), keyRunes("shell"), keyEnter}, nil},
+		{"add tag", []tea.Msg{keyDown, keyRunes("t"), keyRunes("cli, demo"), keyEnter}, nil},
+		{"move", []tea.Msg{keyRunes("R"), keyCtrlU, keyRunes("notes"), keyEnter}, nil},
+		{"move collision", []tea.Msg{keyRunes("R"), keyCtrlU, keyRunes("shell"), keyEnter}, map[string]string{"shell/empty.txt": "taken\n"}},
+		{"move collision suffix", []tea.Msg{keyRunes("R"), keyCtrlU, keyRunes("shell"), keyEnter, keyRunes("s")}, map[string]string{"shell/empty.txt": "taken\n"}},
+		{"move collision overwrite", []tea.Msg{keyRunes("R"), keyCtrlU, keyRunes("shell"), keyEnter, keyRunes("o")}, map[string]string{"shell/empty.txt": "taken\n"}},
+		{"move collision cancel", []tea.Msg{keyRunes("R"), keyCtrlU, keyRunes("shell"), keyEnter, keyEsc}, map[string]string{"shell/empty.txt": "taken\n"}},
+		{"inline edit", []tea.Msg{keyDown, keyRunes("i")}, nil},
+		{"inline edit typing", []tea.Msg{keyDown, keyRunes("i"), keyRunes("// hi"), keyEnter}, nil},
+		{"inline edit preview", []tea.Msg{keyDown, keyRunes("i"), keyRunes("// hi"), keyEnter, keyCtrlR}, nil},
+		{"inline edit save", []tea.Msg{keyDown, keyRunes("i"), keyRunes("// hi"), keyEnter, keyCtrlS}, nil},
+		{"inline edit discard", []tea.Msg{keyDown, keyRunes("i"), keyRunes("// hi"), keyEnter, keyEsc}, nil},
+		{"inline edit discard confirm", []tea.Msg{keyDown, keyRunes("i"), keyRunes("// hi"), keyEnter, keyEsc, keyRunes("y")}, nil},
+		{"inline edit discard keep", []tea.Msg{keyDown, keyRunes("i"), keyRunes("// hi"), keyEnter, keyEsc, keyRunes("N")}, nil},
+		{"inline edit unchanged", []tea.Msg{keyDown, keyRunes("i"), keyEsc}, nil},
+		{"paste history", []tea.Msg{keyDown, keyRunes("c"), keyDown, keyRunes("P")}, nil},
+		{"paste history replace", []tea.Msg{keyDown, keyRunes("c"), keyDown, keyRunes("k"), keyRunes("P"), keyRunes("r")}, nil},
+		{"paste history cancel", []tea.Msg{keyDown, keyRunes("c"), keyDown, keyRunes("P"), keyEsc}, nil},
+		{"narrow", []tea.Msg{tea.WindowSizeMsg{Width: 80, Height: 24}}, nil},
+		{"narrow folder pane", []tea.Msg{tea.WindowSizeMsg{Width: 80, Height: 24}, keyShiftTab}, nil},
+		{"stacked", []tea.Msg{tea.WindowSizeMsg{Width: 50, Height: 24}}, nil},
+		{"zoom", []tea.Msg{keyRunes("z")}, nil},
+		{"zoom snippet pane", []tea.Msg{keyRunes("z"), keyTab}, nil},
+		{"widen pane", []tea.Msg{keyRunes(">"), keyRunes(">"), keyShiftTab, keyRunes("<")}, nil},
+		{"click folder", []tea.Msg{tea.MouseMsg{X: 4, Y: 3, Type: tea.MouseLeft}}, nil},
+		{"click snippet", []tea.Msg{tea.MouseMsg{X: 28, Y: 7, Type: tea.MouseLeft}}, nil},
+		{"click content", []tea.Msg{tea.MouseMsg{X: 80, Y: 5, Type: tea.MouseLeft}}, nil},
+		{"double click folder", []tea.Msg{tea.MouseMsg{X: 4, Y: 3, Type: tea.MouseLeft}, tea.MouseMsg{X: 4, Y: 3, Type: tea.MouseLeft}}, nil},
+		{"wheel snippets", []tea.Msg{tea.MouseMsg{X: 28, Y: 10, Type: tea.MouseWheelDown}}, nil},
+		{"click help", []tea.Msg{tea.MouseMsg{X: 38, Y: 23, Type: tea.MouseLeft}}, nil},
+		{"palette", []tea.Msg{keyRunes(":")}, nil},
+		{"palette search", []tea.Msg{keyRunes(":"), keyRunes("file type")}, nil},
+		{"palette run", []tea.Msg{keyRunes(":"), keyRunes("set file type"), keyEnter}, nil},
+		{"palette snippet", []tea.Msg{keyRunes(":"), keyRunes("readme"), keyEnter}, nil},
+		{"palette close", []tea.Msg{keyRunes(":"), keyDown, keyEsc}, nil},
+		{"sort", []tea.Msg{keyRunes("S")}, nil},
+		{"sort by size", []tea.Msg{keyRunes("S"), keyRunes("S"), keyRunes("S"), keyRunes("S")}, nil},
+		{"wrap", []tea.Msg{keyDown, keyDown, keyRunes("w")}, map[string]string{"misc/long.txt": strings.Repeat("long line ", 15) + "end\nshort\n"}},
+		{"scroll right", []tea.Msg{keyDown, keyDown, keyTab, keyRunes("l"), keyRunes("l")}, map[string]string{"misc/long.txt": strings.Repeat("long line ", 15) + "end\nshort\n"}},
+		{"whitespace", []tea.Msg{keyDown, keyRunes("W")}, nil},
+		{"content search", []tea.Msg{keyDown, keyTab, keyRunes("/"), keyRunes("print")}, nil},
+		{"content search next", []tea.Msg{keyDown, keyTab, keyRunes("/"), keyRunes("main"), keyEnter, keyRunes("n")}, nil},
+		{"content search cancel", []tea.Msg{keyDown, keyTab, keyRunes("/"), keyRunes("main"), keyEsc}, nil},
+		{"markdown", []tea.Msg{keyShiftTab, keyDown, keyEnter, keyDown}, map[string]string{"notes/todo.md": "# Todo\n\n- write *docs*\n- ship\n\n```sh\necho done\n```\n"}},
+		{"markdown raw", []tea.Msg{keyShiftTab, keyDown, keyEnter, keyDown, keyRunes("M")}, map[string]string{"notes/todo.md": "# Todo\n\n- write *docs*\n- ship\n\n```sh\necho done\n```\n"}},
+		{"run confirm", []tea.Msg{keyDown, keyRunes("X")}, map[string]string{"misc/greet.sh": "echo hello {{who:world}}\n"}},
+		{"run placeholder", []tea.Msg{keyDown, keyRunes("X"), keyRunes("y")}, map[string]string{"misc/greet.sh": "echo hello {{who:world}}\n"}},
+		{"run cancel", []tea.Msg{keyDown, keyRunes("X"), keyRunes("y"), keyEsc}, map[string]string{"misc/greet.sh": "echo hello {{who:world}}\n"}},
+		{"diagnostics", []tea.Msg{keyDown, checkedMsg{newSnippet("misc", "hello.go"), []diagnostic{{3, 13, "missing return"}, {4, 0, "undefined: println"}}}}, nil},
+		{"encrypted locked", []tea.Msg{keyDown, keyDown}, map[string]string{"misc/token.sh.age": "age-encryption.org/v1\n"}},
+		{"encrypted unlock", []tea.Msg{keyDown, keyDown, keyRunes("O"), keyRunes("secret")}, map[string]string{"misc/token.sh.age": "age-encryption.org/v1\n"}},
+		{"encrypted wrong passphrase", []tea.Msg{keyDown, keyDown, keyRunes("O"), keyRunes("secret"), keyEnter}, map[string]string{"misc/token.sh.age": "age-encryption.org/v1\n"}},
+		{"sensitive masked", []tea.Msg{keyDown, keyDown}, map[string]string{"misc/token.sh": "export TOKEN=hunter2\n\necho $TOKEN\n", ".snp.yaml": "misc/token.sh:\n  tags: [sensitive]\n"}},
+		{"sensitive reveal", []tea.Msg{keyDown, keyDown, keyRunes("v")}, map[string]string{"misc/token.sh": "export TOKEN=hunter2\n\necho $TOKEN\n", ".snp.yaml": "misc/token.sh:\n  sensitive: true\n"}},
+		{"folder removed", []tea.Msg{keyShiftTab, keyDown, keyEnter, folderRemovedMsg("notes")}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, snippets := testSnippets(t, tt.files)
+			m := newModel(config, snippets)
+			send(t, m, m.Init()(), tea.WindowSizeMsg{Width: 120, Height: 24})
+			send(t, m, tt.msgs...)
+			assertGolden(t, m)
+		})
+	}
+}
+
+func TestViewNoSnippets(t *testing.T) {
+	config := newConfig()
+	config.Root = t.TempDir()
+	config.StateDir = t.TempDir()
+	config.Clipboard = fileClipboard
+	m := newModel(config, nil)
+	send(t, m, m.Init()(), tea.WindowSizeMsg{Width: 120, Height: 24})
+	assertGolden(t, m)
+}
+
+func TestViewEncrypted(t *testing.T) {
+	config, _ := testSnippets(t, nil)
+	identity, err := agecrypt.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Secrets.Identity = filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(config.Secrets.Identity, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k, err := identityKeyring(config.Secrets.Identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.encrypt(filepath.Join(config.Root, "misc", "token.sh.age"), "export TOKEN=hunter2\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newModel(config, readSnippets(config))
+	send(t, m, m.Init()(), tea.WindowSizeMsg{Width: 120, Height: 24})
+	send(t, m, keyDown, keyDown, keyRunes("O"))
+	assertGolden(t, m)
+
+	// The snippet is decrypted by a Cmd rather than within Update.
+	m.source = ""
+	_, cmd := m.Update(updateContentMsg(m.selectedSnippet()))
+	if cmd == nil || m.source != "" {
+		t.Fatal("the snippet was decrypted within Update")
+	}
+	send(t, m, runCmd(t, cmd))
+	if m.source != "export TOKEN=hunter2\n" {
+		t.Errorf("got content %q after decrypting", m.source)
+	}
+}
+
+func TestExportEncrypted(t *testing.T) {
+	tests := []struct {
+		name   string
+		answer tea.KeyMsg
+		want   []string
+		hint   string
+	}{
+		{"include", keyRunes("y"), []string{"hello.go", "token.sh.age"}, ""},
+		{"leave out", keyRunes("N"), []string{"hello.go"}, "Left out 1 encrypted snippet"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, _ := testSnippets(t, map[string]string{"misc/token.sh.age": "not really encrypted"})
+			m := newModel(config, readSnippets(config))
+			send(t, m, m.Init()(), tea.WindowSizeMsg{Width: 120, Height: 24})
+			send(t, m, keyDown, keySpace, keySpace, keyRunes("E"))
+			dir := t.TempDir()
+			m.inputs[promptInput].SetValue(dir)
+			send(t, m, keyEnter)
+			if m.state != confirmingExportState {
+				t.Fatalf("got state %d, want the export to be confirmed", m.state)
+			}
+			send(t, m, tt.answer)
+
+			entries, err := os.ReadDir(filepath.Join(dir, "misc"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exported %v, want %v", got, tt.want)
+			}
+			if view := m.View(); tt.hint != "" && !strings.Contains(view, tt.hint) {
+				t.Errorf("the view does not contain %q", tt.hint)
+			}
+		})
+	}
+}
+
+func TestCaptureClipboard(t *testing.T) {
+	config, snippets := testSnippets(t, nil)
+	if err := os.WriteFile(clipboardFile(config.StateDir), []byte("password\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := newModel(config, snippets)
+	send(t, m, m.Init()(), tea.WindowSizeMsg{Width: 120, Height: 24})
+	// The clipboard is captured when the history is opened, not on start.
+	if _, err := os.Stat(historyFile(config.StateDir)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("the clipboard was captured on start: %v", err)
+	}
+	send(t, m, keyDown, keyRunes("P"))
+	h, err := readHistory(config.StateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 1 || h[0].Content != "password\n" {
+		t.Errorf("got history %+v", h)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// doubleClickTime is the time in which a second click on the same item is a
+// double click.
+const doubleClickTime = 400 * time.Millisecond
+
+const (
+	// folderItemsTop is the first row of the folder items below the title bar.
+	folderItemsTop = 2
+	// snippetItemsTop is the first row of the snippet items below the title
+	// bar and the status bar.
+	snippetItemsTop = 4
+)
+
+// click is a click on an item of a pane, which is remembered to detect double
+// clicks.
+type click struct {
+	pane  pane
+	index int
+	time  time.Time
+}
+
+// keyMsg returns the key message of pressing the key, named as in key
+// bindings.
+func keyMsg(k string) tea.KeyMsg {
+	for t := tea.KeyType(-128); t < 128; t++ {
+		if t == tea.KeySpace && k == " " {
+			return tea.KeyMsg{Type: t, Runes: []rune(k)}
+		}
+		if t != tea.KeyRunes && t.String() == k {
+			return tea.KeyMsg{Type: t}
+		}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+// press performs the action of the key binding as if its key was pressed.
+func (m *Model) press(b key.Binding) tea.Cmd {
+	if !b.Enabled() || len(b.Keys()) <= 0 {
+		return nil
+	}
+	_, cmd := m.Update(keyMsg(b.Keys()[0]))
+	return cmd
+}
+
+// paneAt returns the pane at the position in the terminal and the row of the
+// position within the pane.
+func (m *Model) paneAt(x, y int) (pane, int, bool) {
+	g := m.geometry()
+	top := g.listHeight + 2
+	switch {
+	case g.stacked && y >= top:
+		return contentPane, y - top, y < top+g.contentHeight+2
+	case y >= top:
+		return 0, 0, false
+	case x < g.folders:
+		return folderPane, y, true
+	case x < g.folders+g.snippets:
+		return snippetPane, y, true
+	}
+	return contentPane, y, true
+}
+
+// helpAt returns the key binding of the short help at the column.
+func (m *Model) helpAt(x int) (key.Binding, bool) {
+	x -= marginStyle.GetMarginLeft()
+	sep := lipgloss.Width(m.help.ShortSeparator)
+	pos := 0
+	for _, b := range m.keys.ShortHelp() {
+		if !b.Enabled() {
+			continue
+		}
+		if pos > 0 {
+			pos += sep
+		}
+		w := lipgloss.Width(b.Help().Key + " " + b.Help().Desc)
+		if x >= pos && x < pos+w {
+			return b, true
+		}
+		pos += w
+	}
+	return key.Binding{}, false
+}
+
+// itemAt returns the index of the visible item of the list at the row, where
+// top is the row of the first item.
+func itemAt(li *list.Model, row, top int, d list.ItemDelegate) (int, bool) {
+	row -= top
+	step := d.Height() + d.Spacing()
+	if row < 0 || row%step >= d.Height() {
+		return 0, false
+	}
+	i := li.Paginator.Page*li.Paginator.PerPage + row/step
+	return i, i < len(li.VisibleItems())
+}
+
+// focusPane makes the pane the active pane.
+func (m *Model) focusPane(p pane) {
+	m.pane = p
+	m.updateKeyMap()
+	m.resize()
+	m.updateStyles()
+}
+
+// updateMouse handles the mouse message. Clicks focus panes and select
+// items, double clicks open them and the wheel scrolls the pane under the
+// pointer. Clicking an entry of the help performs its action.
+func (m *Model) updateMouse(msg tea.MouseMsg) tea.Cmd {
+	if m.state != navigatingState || m.List().FilterState() == list.Filtering {
+		return nil
+	}
+
+	if msg.Type == tea.MouseLeft && !m.help.ShowAll && msg.Y == m.height+3 {
+		if b, ok := m.helpAt(msg.X); ok {
+			return m.press(b)
+		}
+		return nil
+	}
+
+	p, row, ok := m.paneAt(msg.X, msg.Y)
+	if !ok {
+		return nil
+	}
+
+	switch msg.Type {
+	case tea.MouseWheelUp, tea.MouseWheelDown:
+		up := msg.Type == tea.MouseWheelUp
+		switch p {
+		case folderPane:
+			if up {
+				m.Folders.CursorUp()
+			} else {
+				m.Folders.CursorDown()
+			}
+			m.updateStyles()
+			return m.updateContent()
+		case snippetPane:
+			if up {
+				m.List().CursorUp()
+			} else {
+				m.List().CursorDown()
+			}
+			return m.updateContent()
+		case contentPane:
+			if up {
+				m.Code.LineUp(m.Code.MouseWheelDelta)
+				m.LineNumbers.LineUp(m.Code.MouseWheelDelta)
+			} else {
+				m.Code.LineDown(m.Code.MouseWheelDelta)
+				m.LineNumbers.LineDown(m.Code.MouseWheelDelta)
+			}
+		}
+		return nil
+	case tea.MouseLeft:
+		m.focusPane(p)
+		var (
+			i      int
+			hit    bool
+			action key.Binding
+		)
+		switch p {
+		case folderPane:
+			if i, hit = itemAt(&m.Folders, row, folderItemsTop, folderDelegate{}); hit {
+				m.Folders.Select(i)
+				action = m.keys.ChangeFolder
+			}
+		case snippetPane:
+			if i, hit = itemAt(m.List(), row, snippetItemsTop, snippetDelegate{}); hit {
+				m.List().Select(i)
+				action = m.keys.EditSnippet
+			}
+		}
+		if !hit {
+			return nil
+		}
+		m.updateKeyMap()
+		m.updateStyles()
+		now := time.Now()
+		last := m.lastClick
+		m.lastClick = click{p, i, now}
+		if last.pane == p && last.index == i && now.Sub(last.time) < doubleClickTime {
+			m.lastClick = click{}
+			return tea.Batch(m.updateContent(), m.press(action))
+		}
+		return m.updateContent()
+	}
+	return nil
+}
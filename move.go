@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pendingMove is a move of a snippet that collided with another snippet.
+type pendingMove struct {
+	snippet Snippet
+	folder  string
+	file    string
+}
+
+// moveSnippet moves the snippet to the folder and file name and selects it in
+// its new folder. If the file name is taken and the collision is
+// failOnCollision, the user is asked how to resolve the collision.
+func (m *Model) moveSnippet(s Snippet, folder, file string, c collision) tea.Cmd {
+	moved, err := moveSnippetFile(m.config.Root, s, folder, file, c)
+	if errors.Is(err, errSnippetExists) {
+		m.pendingMove = &pendingMove{snippet: s, folder: folder, file: file}
+		m.state = collidingState
+		m.pane = snippetPane
+		m.updateKeyMap()
+		return nil
+	}
+	if err != nil {
+		m.displayError(err.Error())
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	if c == overwriteOnCollision {
+		if li, ok := m.Lists[Folder(moved.Folder)]; ok {
+			if i := indexOfSnippet(li, moved); i >= 0 && (s.Folder != moved.Folder || s.File != moved.File) {
+				li.RemoveItem(i)
+			}
+		}
+	}
+	cmds = append(cmds, m.replaceSnippet(s, moved))
+	if li, ok := m.Lists[Folder(moved.Folder)]; ok {
+		li.Select(indexOfSnippet(li, moved))
+	}
+	return tea.Batch(append(cmds, m.selectFolder(Folder(moved.Folder)), m.updateContent())...)
+}
+
+// resolveCollision finishes the pending move by handling the collision as
+// chosen by the user. failOnCollision cancels the move.
+func (m *Model) resolveCollision(c collision) tea.Cmd {
+	move := m.pendingMove
+	m.pendingMove = nil
+	m.state = navigatingState
+	m.updateKeyMap()
+	if move == nil || c == failOnCollision {
+		return m.updateContent()
+	}
+	return m.moveSnippet(move.snippet, move.folder, move.file, c)
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// command is an entry of the command palette: an action, a folder or a
+// snippet.
+type command struct {
+	title string
+	// hint is the key binding of an action, or the kind of the entry.
+	hint string
+	run  func() tea.Cmd
+}
+
+// commands returns the entries of the command palette for the current state.
+// Actions are only listed when their key bindings are enabled.
+func (m *Model) commands() []command {
+	var commands []command
+	seen := map[string]bool{m.keys.Palette.Help().Desc: true}
+	// The actions come first, as the palette is mostly used to find them.
+	groups := [][]key.Binding{
+		m.keys.snippetKeys(),
+		m.keys.fileKeys(),
+		m.keys.folderKeys(),
+		m.keys.markKeys(),
+		m.keys.paneKeys(),
+		m.keys.contentKeys(),
+		m.keys.navigationKeys(),
+	}
+	for _, group := range groups {
+		for _, b := range group {
+			desc := b.Help().Desc
+			if !b.Enabled() || seen[desc] {
+				continue
+			}
+			seen[desc] = true
+			b := b
+			commands = append(commands, command{desc, b.Help().Key, func() tea.Cmd { return m.press(b) }})
+		}
+	}
+	commands = append(commands,
+		command{"copy path", "", m.copyPath},
+		command{"reset layout", "", m.resetLayout},
+	)
+
+	folders := maps.Keys(m.Lists)
+	slices.Sort(folders)
+	for _, f := range folders {
+		f := f
+		commands = append(commands, command{string(f), "folder", func() tea.Cmd {
+			return m.selectFolder(f)
+		}})
+	}
+	for _, f := range folders {
+		for _, item := range m.Lists[f].Items() {
+			s, ok := item.(Snippet)
+			if !ok {
+				continue
+			}
+			commands = append(commands, command{s.Folder + "/" + s.File, "snippet", func() tea.Cmd {
+				return m.selectSnippet(s)
+			}})
+		}
+	}
+	return commands
+}
+
+// matchingCommands returns the commands that fuzzily match the query of the
+// palette, best matches first.
+func (m *Model) matchingCommands() []command {
+	query := strings.TrimSpace(m.inputs[paletteInput].Value())
+	if query == "" {
+		return m.palette
+	}
+	titles := make([]string, len(m.palette))
+	for i, c := range m.palette {
+		titles[i] = c.title
+	}
+	var commands []command
+	for _, match := range fuzzy.Find(query, titles) {
+		commands = append(commands, m.palette[match.Index])
+	}
+	return commands
+}
+
+// openPalette opens the command palette in the content pane.
+func (m *Model) openPalette() tea.Cmd {
+	m.palette = m.commands()
+	m.paletteIndex = 0
+	m.state = paletteState
+	m.inputs[paletteInput].SetValue("")
+	m.updateKeyMap()
+	return m.focusInput(paletteInput)
+}
+
+// closePalette closes the command palette.
+func (m *Model) closePalette() {
+	m.palette = nil
+	m.state = navigatingState
+	m.blurInputs()
+	m.updateKeyMap()
+}
+
+// updatePalette handles the key message while the command palette is open.
+func (m *Model) updatePalette(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keys.RunCommand):
+		commands := m.matchingCommands()
+		if m.paletteIndex >= len(commands) {
+			return nil
+		}
+		m.closePalette()
+		return tea.Batch(commands[m.paletteIndex].run(), m.updateContent())
+	case key.Matches(msg, m.keys.ClosePalette):
+		m.closePalette()
+		return nil
+	}
+	switch msg.String() {
+	case "up", "ctrl+p":
+		if m.paletteIndex > 0 {
+			m.paletteIndex--
+		}
+		return nil
+	case "down", "ctrl+n":
+		if m.paletteIndex < len(m.matchingCommands())-1 {
+			m.paletteIndex++
+		}
+		return nil
+	}
+	var cmd tea.Cmd
+	m.inputs[paletteInput], cmd = m.inputs[paletteInput].Update(msg)
+	m.paletteIndex = 0
+	return cmd
+}
+
+// paletteView returns the view of the commands that match the query with the
+// selected command highlighted.
+func (m *Model) paletteView() string {
+	commands := m.matchingCommands()
+	if len(commands) <= 0 {
+		return m.ContentStyle.EmptyHint.Render("No matching commands.")
+	}
+
+	start := 0
+	if m.paletteIndex >= m.Code.Height && m.Code.Height > 0 {
+		start = m.paletteIndex - m.Code.Height + 1
+	}
+	var lines []string
+	for i := start; i < len(commands) && (m.Code.Height <= 0 || i < start+m.Code.Height); i++ {
+		c := commands[i]
+		style := m.ContentStyle.EmptyHint
+		if i == m.paletteIndex {
+			style = m.ContentStyle.EmptyHintKey
+		}
+		lines = append(lines, style.Render(fmt.Sprintf("%-30s", c.title))+" "+m.ContentStyle.LineNumber.Render(c.hint))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// selectSnippet selects the folder of the snippet and the snippet in it.
+func (m *Model) selectSnippet(s Snippet) tea.Cmd {
+	cmd := m.selectFolder(Folder(s.Folder))
+	m.List().ResetFilter()
+	if i := indexOfSnippet(m.List(), s); i >= 0 {
+		m.List().Select(i)
+	}
+	m.focusPane(snippetPane)
+	return cmd
+}
+
+// copyPath copies the path of the selected snippet file to the clipboard.
+func (m *Model) copyPath() tea.Cmd {
+	path := m.selectedSnippetFilePath()
+	return func() tea.Msg {
+		if err := m.clipboard.WriteAll(path); err != nil {
+			return errorMsg{fmt.Errorf("copy to %s clipboard: %w", m.clipboard.Name(), err)}
+		}
+		return nil
+	}
+}
+
+// resetLayout restores the default widths of the panes.
+func (m *Model) resetLayout() tea.Cmd {
+	m.layout = defaultLayout
+	m.resize()
+	m.updateStyles()
+	return m.saveLayout()
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+	"golang.org/x/exp/slices"
+)
+
+// defaultTabWidth is the number of columns that tabs are expanded to unless
+// configured otherwise.
+const defaultTabWidth = 4
+
+// scrollStep is the number of columns that the content pane scrolls
+// horizontally by.
+const scrollStep = 8
+
+// Escape sequences that are written around search matches and at the end of
+// wrapped lines.
+const (
+	reverseOn  = "\x1b[7m"
+	reverseOff = "\x1b[27m"
+	resetStyle = "\x1b[0m"
+)
+
+// Markers of whitespace that is made visible.
+const (
+	spaceMarker = "·"
+	tabMarker   = "→"
+)
+
+// cell is a visible rune of a line with the escape sequences in front of it.
+type cell struct {
+	esc string
+	r   rune
+}
+
+// splitCells splits the line into its visible runes and the escape sequences
+// that are left at its end.
+func splitCells(line string) ([]cell, string) {
+	var (
+		cells []cell
+		esc   strings.Builder
+	)
+	for i := 0; i < len(line); {
+		if line[i] == '\x1b' {
+			j := i + 1
+			if j < len(line) && line[j] == '[' {
+				j++
+				for j < len(line) && (line[j] < 0x40 || line[j] > 0x7e) {
+					j++
+				}
+				if j < len(line) {
+					j++
+				}
+			}
+			esc.WriteString(line[i:j])
+			i = j
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		cells = append(cells, cell{esc.String(), r})
+		esc.Reset()
+		i += size
+	}
+	return cells, esc.String()
+}
+
+// activeStyle returns the escape sequences that are in effect after the
+// escape sequences following the active ones.
+func activeStyle(active, esc string) string {
+	for _, seq := range strings.SplitAfter(esc, "m") {
+		if seq == resetStyle || seq == "\x1b[m" {
+			active = ""
+		} else {
+			active += seq
+		}
+	}
+	return active
+}
+
+// highlightMatches highlights the matches of the term in the line, ignoring
+// case and the escape sequences of the line. It reports whether there were any
+// matches.
+func highlightMatches(line, term string) (string, bool) {
+	cells, tail := splitCells(line)
+	runes := make([]rune, len(cells))
+	for i, c := range cells {
+		runes[i] = unicode.ToLower(c.r)
+	}
+	needle := []rune(strings.ToLower(term))
+	matched := make([]bool, len(cells))
+	found := false
+	for i := 0; len(needle) > 0 && i+len(needle) <= len(runes); {
+		if !slices.Equal(runes[i:i+len(needle)], needle) {
+			i++
+			continue
+		}
+		for j := i; j < i+len(needle); j++ {
+			matched[j] = true
+		}
+		found = true
+		i += len(needle)
+	}
+	if !found {
+		return line, false
+	}
+
+	var b strings.Builder
+	for i, c := range cells {
+		if !matched[i] && i > 0 && matched[i-1] {
+			b.WriteString(reverseOff)
+		}
+		b.WriteString(c.esc)
+		// Escape sequences may reset the style, so the highlight is
+		// written again after them.
+		if matched[i] && (i == 0 || !matched[i-1] || c.esc != "") {
+			b.WriteString(reverseOn)
+		}
+		b.WriteRune(c.r)
+	}
+	if matched[len(cells)-1] {
+		b.WriteString(reverseOff)
+	}
+	b.WriteString(tail)
+	return b.String(), true
+}
+
+// wrapLine breaks the line into rows of at most the width, carrying the
+// style of the line over to the next row.
+func wrapLine(line string, width int) []string {
+	cells, tail := splitCells(line)
+	var (
+		rows   []string
+		b      strings.Builder
+		active string
+		w      int
+	)
+	for _, c := range cells {
+		rw := runewidth.RuneWidth(c.r)
+		if w+rw > width && w > 0 {
+			b.WriteString(resetStyle)
+			rows = append(rows, b.String())
+			b.Reset()
+			b.WriteString(active)
+			w = 0
+		}
+		active = activeStyle(active, c.esc)
+		b.WriteString(c.esc)
+		b.WriteRune(c.r)
+		w += rw
+	}
+	b.WriteString(tail)
+	return append(rows, b.String())
+}
+
+// scrollLine drops the columns of the line before the offset, keeping the
+// style of the line.
+func scrollLine(line string, offset int) string {
+	cells, tail := splitCells(line)
+	var b strings.Builder
+	w := 0
+	for _, c := range cells {
+		b.WriteString(c.esc)
+		if w >= offset {
+			b.WriteRune(c.r)
+		}
+		w += runewidth.RuneWidth(c.r)
+	}
+	b.WriteString(tail)
+	return b.String()
+}
+
+// expandWhitespace replaces the tabs of the line with spaces up to the tab
+// width, making tabs and spaces visible if asked to.
+func expandWhitespace(line string, tabWidth int, visible bool) string {
+	tab := strings.Repeat(" ", tabWidth)
+	if visible {
+		line = strings.ReplaceAll(line, " ", spaceMarker)
+		tab = tabMarker + strings.Repeat(" ", tabWidth-1)
+	}
+	return strings.ReplaceAll(line, "\t", tab)
+}
+
+// renderContent renders the highlighted snippet into the content pane with
+// its line numbers, wrapping or scrolling long lines and highlighting the
+// matches of the search. Encrypted and masked snippets are not searched.
+func (m *Model) renderContent() {
+	if m.highlighted == "" {
+		return
+	}
+
+	var code, numbers []string
+	m.matches = nil
+	m.rowLines = nil
+	m.contentWidth = 0
+	diagnostics := m.previewedDiagnostics()
+	lines := strings.Split(m.highlighted, "\n")
+	for i, line := range lines {
+		if m.searchTerm != "" && !m.previewed.Encrypted && !m.masked() {
+			var found bool
+			if line, found = highlightMatches(line, m.searchTerm); found {
+				m.matches = append(m.matches, len(code))
+			}
+		}
+		line = expandWhitespace(line, m.config.Preview.tabWidth(), m.whitespace)
+		if w := runewidth.StringWidth(ansiStripped(line)); w > m.contentWidth {
+			m.contentWidth = w
+		}
+
+		rows := []string{line}
+		if m.wrap && m.Code.Width > 0 {
+			rows = wrapLine(line, m.Code.Width)
+		} else if m.scrollX > 0 {
+			rows = []string{scrollLine(line, m.scrollX)}
+		}
+		for j, row := range rows {
+			switch {
+			case i == len(lines)-1 && j == 0:
+				numbers = append(numbers, "  ~ ")
+			case j == 0:
+				numbers = append(numbers, fmt.Sprintf("%3d ", i+1))
+			default:
+				numbers = append(numbers, "    ")
+			}
+			code = append(code, row)
+			m.rowLines = append(m.rowLines, i)
+		}
+		// The problems of a line are shown below it.
+		for len(diagnostics) > 0 && diagnostics[0].line <= i+1 {
+			numbers = append(numbers, "  ! ")
+			code = append(code, m.ContentStyle.Diagnostic.Render(diagnostics[0].String()))
+			m.rowLines = append(m.rowLines, i)
+			diagnostics = diagnostics[1:]
+		}
+	}
+	m.Code.SetContent(strings.Join(code, "\n"))
+	m.LineNumbers.SetContent(strings.Join(numbers, "\n"))
+}
+
+// lineAt returns the index of the snippet line that is shown in the row of the
+// content pane.
+func (m *Model) lineAt(row int) int {
+	if row < 0 || row >= len(m.rowLines) {
+		return row
+	}
+	return m.rowLines[row]
+}
+
+// ansiStripped returns the line without its escape sequences.
+func ansiStripped(line string) string {
+	cells, _ := splitCells(line)
+	runes := make([]rune, len(cells))
+	for i, c := range cells {
+		runes[i] = c.r
+	}
+	return string(runes)
+}
+
+// toggleWrap switches soft wrapping of long lines on or off.
+func (m *Model) toggleWrap() {
+	m.wrap = !m.wrap
+	m.scrollX = 0
+	m.renderContent()
+	m.updateKeyMap()
+}
+
+// toggleWhitespace switches showing spaces and tabs on or off.
+func (m *Model) toggleWhitespace() {
+	m.whitespace = !m.whitespace
+	m.renderContent()
+}
+
+// scrollHorizontally scrolls the content pane by the number of columns, up to
+// the end of the longest line.
+func (m *Model) scrollHorizontally(delta int) {
+	m.scrollX += delta
+	if limit := m.contentWidth - m.Code.Width; m.scrollX > limit {
+		m.scrollX = limit
+	}
+	if m.scrollX < 0 {
+		m.scrollX = 0
+	}
+	m.renderContent()
+}
+
+// startContentSearch opens the search input of the content pane.
+func (m *Model) startContentSearch() tea.Cmd {
+	m.state = contentSearchState
+	m.inputs[searchInput].SetValue(m.searchTerm)
+	m.updateKeyMap()
+	return m.focusInput(searchInput)
+}
+
+// updateContentSearch handles the key message while searching in the content
+// pane. The matches are highlighted as the term is typed.
+func (m *Model) updateContentSearch(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "enter", "esc":
+		if msg.String() == "esc" {
+			m.search("")
+		}
+		m.state = navigatingState
+		m.blurInputs()
+		m.updateKeyMap()
+		return nil
+	}
+	var cmd tea.Cmd
+	m.inputs[searchInput], cmd = m.inputs[searchInput].Update(msg)
+	m.search(m.inputs[searchInput].Value())
+	return cmd
+}
+
+// search highlights the matches of the term in the content pane and scrolls
+// to the first one.
+func (m *Model) search(term string) {
+	m.searchTerm = term
+	m.matchIndex = 0
+	m.renderContent()
+	m.showMatch()
+}
+
+// nextMatch scrolls to the match after the current one, or before it when
+// delta is negative.
+func (m *Model) nextMatch(delta int) {
+	if len(m.matches) <= 0 {
+		return
+	}
+	m.matchIndex = (m.matchIndex + delta + len(m.matches)) % len(m.matches)
+	m.showMatch()
+}
+
+// showMatch scrolls the current match into view.
+func (m *Model) showMatch() {
+	if m.matchIndex >= len(m.matches) {
+		return
+	}
+	row := m.matches[m.matchIndex]
+	if row >= m.Code.YOffset && row < m.Code.YOffset+m.Code.Height {
+		return
+	}
+	offset := row - m.Code.Height/2
+	if offset < 0 {
+		offset = 0
+	}
+	m.Code.SetYOffset(offset)
+	m.LineNumbers.SetYOffset(offset)
+}
+
+// searchStatus returns the term that is searched for and the position of the
+// current match.
+func (m *Model) searchStatus() string {
+	if len(m.matches) <= 0 {
+		return fmt.Sprintf("/%s (no matches)", m.searchTerm)
+	}
+	return fmt.Sprintf("/%s (%d/%d)", m.searchTerm, m.matchIndex+1, len(m.matches))
+}
+
+// updatePreview handles the keys that change how the content pane previews
+// the snippet. It reports whether the key was handled.
+func (m *Model) updatePreview(msg tea.KeyMsg) bool {
+	switch {
+	case key.Matches(msg, m.keys.ToggleWrap):
+		m.toggleWrap()
+	case key.Matches(msg, m.keys.ToggleSpaces):
+		m.toggleWhitespace()
+	case key.Matches(msg, m.keys.ToggleMarkdown):
+		m.toggleMarkdown()
+	case key.Matches(msg, m.keys.Reveal):
+		m.toggleReveal()
+	case key.Matches(msg, m.keys.ScrollLeft):
+		m.scrollHorizontally(-scrollStep)
+	case key.Matches(msg, m.keys.ScrollRight):
+		m.scrollHorizontally(scrollStep)
+	case key.Matches(msg, m.keys.NextMatch):
+		m.nextMatch(1)
+	case key.Matches(msg, m.keys.PreviousMatch):
+		m.nextMatch(-1)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultRunners are the command templates that snippets of well-known
+// languages are run with.
+var defaultRunners = map[string]string{
+	"sh":   "bash {file}",
+	"bash": "bash {file}",
+	"zsh":  "zsh {file}",
+	"fish": "fish {file}",
+	"py":   "python3 {file}",
+	"go":   "go run {file}",
+	"js":   "node {file}",
+	"rb":   "ruby {file}",
+	"pl":   "perl {file}",
+	"lua":  "lua {file}",
+	"php":  "php {file}",
+}
+
+var errNoRunner = errors.New("no runner")
+
+// placeholderPattern matches the placeholders of snippets that are asked for
+// before running them, written as {{name}} or {{name:default}}.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*(?::([^}]*))?\}\}`)
+
+// placeholder is a value that is asked for before running a snippet.
+type placeholder struct {
+	name  string
+	value string
+}
+
+// placeholders returns the placeholders of the content in the order they
+// first appear, with their default values.
+func placeholders(content string) []placeholder {
+	var (
+		result []placeholder
+		seen   = map[string]bool{}
+	)
+	for _, match := range placeholderPattern.FindAllStringSubmatch(content, -1) {
+		if seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		result = append(result, placeholder{match[1], match[2]})
+	}
+	return result
+}
+
+// fillPlaceholders replaces the placeholders of the content with their
+// values.
+func fillPlaceholders(content string, values []placeholder) string {
+	return placeholderPattern.ReplaceAllStringFunc(content, func(s string) string {
+		name := placeholderPattern.FindStringSubmatch(s)[1]
+		for _, p := range values {
+			if p.name == name {
+				return p.value
+			}
+		}
+		return s
+	})
+}
+
+// runnerTemplate returns the command template that snippets of the language
+// are run with, in which {file} is replaced by the path of the snippet file
+// and {args} by the arguments.
+func runnerTemplate(config Config, language string) (string, error) {
+	if t := strings.TrimSpace(config.Runners[language]); t != "" {
+		return t, nil
+	}
+	if t, ok := defaultRunners[language]; ok {
+		return t, nil
+	}
+	return "", fmt.Errorf("%w for %s snippets, configure one in runners", errNoRunner, language)
+}
+
+// runnerCommand returns the command that runs the snippet file with the
+// arguments. The arguments are appended unless the template places them.
+func runnerCommand(ctx context.Context, config Config, language, path string, args []string) (*exec.Cmd, error) {
+	t, err := runnerTemplate(config, language)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		command []string
+		hasArgs bool
+	)
+	for _, arg := range splitCommand(t) {
+		if arg == "{args}" {
+			command = append(command, args...)
+			hasArgs = true
+			continue
+		}
+		command = append(command, strings.ReplaceAll(arg, "{file}", path))
+	}
+	if !hasArgs {
+		command = append(command, args...)
+	}
+	if len(command) <= 0 {
+		return nil, fmt.Errorf("%w for %s snippets", errNoRunner, language)
+	}
+	return exec.CommandContext(ctx, command[0], command[1:]...), nil
+}
+
+// runnableFile returns the path of a file to run that holds the content with
+// its placeholders filled in, and a function that removes the file. Snippets
+// without placeholders are run in place, unless they are encrypted, whose
+// decrypted copies are written to plaintextDir.
+func runnableFile(path, content string, values []placeholder) (string, func(), error) {
+	if len(values) <= 0 && !strings.HasSuffix(path, encryptedExt) {
+		return path, func() {}, nil
+	}
+	dir, err := os.MkdirTemp(plaintextDir(), "snp-run-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	file := filepath.Join(dir, strings.TrimSuffix(filepath.Base(path), encryptedExt))
+	if err := os.WriteFile(file, []byte(fillPlaceholders(content, values)), 0700); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return file, cleanup, nil
+}
+
+// pendingRun is a run of a snippet that waits for confirmation and the values
+// of its placeholders, or is running.
+type pendingRun struct {
+	snippet Snippet
+	content string
+	values  []placeholder
+	// asked is the number of placeholders that were asked for.
+	asked  int
+	cancel context.CancelFunc
+	// output is the output of the run so far, and exit the exit code once it
+	// finished.
+	output string
+	exit   int
+	done   bool
+}
+
+// runFinishedMsg tells the application that a run finished with the output
+// and exit code.
+type runFinishedMsg struct {
+	run    *pendingRun
+	output string
+	exit   int
+}
+
+// confirmRun asks for confirmation to run the selected snippet.
+func (m *Model) confirmRun() tea.Cmd {
+	s := m.selectedSnippet()
+	if _, err := runnerTemplate(m.config, s.Language); err != nil {
+		return func() tea.Msg { return errorMsg{err} }
+	}
+	content, err := m.readSnippet(s)
+	if err != nil {
+		return func() tea.Msg { return errorMsg{err} }
+	}
+	m.run = &pendingRun{snippet: s, content: content, values: placeholders(content)}
+	m.state = confirmingRunState
+	m.updateKeyMap()
+	return nil
+}
+
+// askPlaceholder asks for the value of the next placeholder of the run, or
+// starts it once all were asked for.
+func (m *Model) askPlaceholder() tea.Cmd {
+	if m.run.asked >= len(m.run.values) {
+		return m.startRun()
+	}
+	m.state = runArgsState
+	m.inputs[promptInput].SetValue(m.run.values[m.run.asked].value)
+	m.updateKeyMap()
+	return m.focusInput(promptInput)
+}
+
+// startRun runs the snippet, capturing its output to show in the content
+// pane.
+func (m *Model) startRun() tea.Cmd {
+	r := m.run
+	m.state = runningState
+	m.blurInputs()
+	m.updateKeyMap()
+	m.pane = contentPane
+	m.updateStyles()
+	_ = recordUse(m.config.StateDir, r.snippet)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	m.showOutput()
+	path := filepath.Join(m.config.Root, r.snippet.Folder, r.snippet.File)
+	return func() tea.Msg {
+		defer cancel()
+		file, cleanup, err := runnableFile(path, r.content, r.values)
+		if err != nil {
+			return runFinishedMsg{r, err.Error(), -1}
+		}
+		defer cleanup()
+		cmd, err := runnerCommand(ctx, m.config, r.snippet.Language, file, nil)
+		if err != nil {
+			return runFinishedMsg{r, err.Error(), -1}
+		}
+		cmd.Dir = m.Workdir
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		err = cmd.Run()
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(err, &exitErr):
+			return runFinishedMsg{r, out.String(), exitErr.ExitCode()}
+		case err != nil:
+			return runFinishedMsg{r, out.String() + err.Error(), -1}
+		}
+		return runFinishedMsg{r, out.String(), 0}
+	}
+}
+
+// finishRun shows the output of the run that finished, unless its output was
+// closed.
+func (m *Model) finishRun(msg runFinishedMsg) {
+	if m.run == nil || m.run != msg.run {
+		return
+	}
+	m.run.output = msg.output
+	m.run.exit = msg.exit
+	m.run.done = true
+	if m.state == runningState {
+		m.showOutput()
+	}
+}
+
+// showOutput shows the output of the run in the content pane.
+func (m *Model) showOutput() {
+	output := strings.TrimRight(m.run.output, "\n")
+	if output == "" && m.run.done {
+		output = "(no output)"
+	} else if output == "" {
+		output = "Running..."
+	}
+	m.previewed = Snippet{}
+	m.highlighted = output + "\n"
+	m.scrollX = 0
+	m.renderContent()
+	m.Code.GotoTop()
+	m.LineNumbers.GotoTop()
+}
+
+// stopRun closes the output of the run, cancelling the run if it did not
+// finish yet.
+func (m *Model) stopRun() tea.Cmd {
+	if m.run != nil && m.run.cancel != nil {
+		m.run.cancel()
+	}
+	m.run = nil
+	m.state = navigatingState
+	m.blurInputs()
+	m.updateKeyMap()
+	return m.updateContent()
+}
+
+// runStatus returns the status of the run for the header of the content
+// pane.
+func (m *Model) runStatus() string {
+	switch {
+	case !m.run.done:
+		return "running"
+	case m.run.exit == 0:
+		return "done"
+	}
+	return fmt.Sprintf("exit %d", m.run.exit)
+}
+
+// updateRun handles the key message while a run is confirmed, asks for its
+// placeholders or shows its output.
+func (m *Model) updateRun(msg tea.KeyMsg) tea.Cmd {
+	switch m.state {
+	case confirmingRunState:
+		switch {
+		case key.Matches(msg, m.keys.Confirm):
+			return m.askPlaceholder()
+		case key.Matches(msg, m.keys.Quit, m.keys.Cancel):
+			return m.stopRun()
+		}
+		return nil
+	case runArgsState:
+		switch msg.String() {
+		case "esc":
+			return m.stopRun()
+		case "enter":
+			m.run.values[m.run.asked].value = m.inputs[promptInput].Value()
+			m.run.asked++
+			return m.askPlaceholder()
+		}
+		var cmd tea.Cmd
+		m.inputs[promptInput], cmd = m.inputs[promptInput].Update(msg)
+		return cmd
+	}
+
+	if key.Matches(msg, m.keys.CloseOutput) {
+		return m.stopRun()
+	}
+	var cmd tea.Cmd
+	m.Code, cmd = m.Code.Update(msg)
+	m.LineNumbers, _ = m.LineNumbers.Update(msg)
+	return cmd
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPlaceholders(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []placeholder
+	}{
+		{"echo hello", nil},
+		{"echo {{name}}", []placeholder{{"name", ""}}},
+		{"echo {{ greeting }} {{name:hello world}}", []placeholder{{"greeting", ""}, {"name", "hello world"}}},
+		// Repeated placeholders are asked for once, with their first default.
+		{"{{a:1}} {{b}} {{a:2}} {{b:3}}", []placeholder{{"a", "1"}, {"b", ""}}},
+		{"echo {{}} {{1a}} ${HOME} {single}", nil},
+	}
+	for _, tt := range tests {
+		if got := placeholders(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("placeholders(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestFillPlaceholders(t *testing.T) {
+	values := []placeholder{{"a", "1"}, {"b", "two words"}}
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"echo {{a}} {{b:x}} {{ a }}", "echo 1 two words 1"},
+		{"echo {{c}} {{c:3}}", "echo {{c}} {{c:3}}"},
+		{"echo ${a}", "echo ${a}"},
+	}
+	for _, tt := range tests {
+		if got := fillPlaceholders(tt.content, values); got != tt.want {
+			t.Errorf("fillPlaceholders(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestRunnerCommand(t *testing.T) {
+	config := newConfig()
+	config.Runners = map[string]string{
+		"py":  "python3 -u {file}",
+		"awk": "awk -f {file} {args} -",
+		"txt": " ",
+	}
+	tests := []struct {
+		language string
+		args     []string
+		want     []string
+		err      bool
+	}{
+		{"sh", []string{"a", "b c"}, []string{"bash", "/tmp/s", "a", "b c"}, false},
+		{"py", nil, []string{"python3", "-u", "/tmp/s"}, false},
+		{"awk", []string{"x"}, []string{"awk", "-f", "/tmp/s", "x", "-"}, false},
+		{"awk", nil, []string{"awk", "-f", "/tmp/s", "-"}, false},
+		{"txt", nil, nil, true},
+		{"nope", nil, nil, true},
+	}
+	for _, tt := range tests {
+		cmd, err := runnerCommand(context.Background(), config, tt.language, "/tmp/s", tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("runnerCommand(%s) error = %v, want error %t", tt.language, err, tt.err)
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, errNoRunner) {
+				t.Errorf("runnerCommand(%s) error = %v, want %v", tt.language, err, errNoRunner)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(cmd.Args, tt.want) {
+			t.Errorf("runnerCommand(%s, %q) = %q, want %q", tt.language, tt.args, cmd.Args, tt.want)
+		}
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	config, snippets := testSnippets(t, map[string]string{
+		"shell/fail.sh": "exit {{code:3}}\n",
+	})
+	config.Runners = map[string]string{"sh": "sh {file}"}
+	// The snippet that is run reads the config from the environment.
+	t.Setenv("SNP_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	t.Setenv("SNP_ROOT", config.Root)
+
+	tests := []struct {
+		args []string
+		exit int
+	}{
+		{[]string{"shell/fail.sh"}, 3},
+		{[]string{"--set", "code=5", "shell/fail.sh"}, 5},
+		{[]string{"--set", "code=0", "shell/fail.sh"}, 0},
+	}
+	for _, tt := range tests {
+		err := runCommand(config, snippets, tt.args)
+		exit := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exit = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if exit != tt.exit {
+			t.Errorf("runCommand(%q) exited with %d, want %d", tt.args, exit, tt.exit)
+		}
+	}
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	agecrypt "filippo.io/age"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/term"
+)
+
+// errLocked is returned when an encrypted snippet is read or written before
+// the encrypted snippets were unlocked.
+var errLocked = errors.New("encrypted snippets are locked")
+
+// keyring decrypts and encrypts the encrypted snippets with a passphrase or
+// the identities of an identity file.
+type keyring struct {
+	identities []agecrypt.Identity
+	recipients []agecrypt.Recipient
+
+	// cache holds the decrypted contents of the snippet files by path, since
+	// decrypting with a passphrase is slow on purpose.
+	mu    sync.Mutex
+	cache map[string]decrypted
+}
+
+// decrypted is the decrypted contents of a snippet file as of its
+// modification time.
+type decrypted struct {
+	modTime time.Time
+	content string
+}
+
+// passphraseKeyring returns a keyring that encrypts with the passphrase.
+func passphraseKeyring(passphrase string) (*keyring, error) {
+	identity, err := agecrypt.NewScryptIdentity(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := agecrypt.NewScryptRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &keyring{
+		identities: []agecrypt.Identity{identity},
+		recipients: []agecrypt.Recipient{recipient},
+		cache:      map[string]decrypted{},
+	}, nil
+}
+
+// identityKeyring returns a keyring that encrypts to the identities of the
+// identity file.
+func identityKeyring(path string) (*keyring, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	identities, err := agecrypt.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	k := &keyring{identities: identities, cache: map[string]decrypted{}}
+	for _, identity := range identities {
+		if x, ok := identity.(*agecrypt.X25519Identity); ok {
+			k.recipients = append(k.recipients, x.Recipient())
+		}
+	}
+	return k, nil
+}
+
+// cliKeyring returns the keyring of the identity file, or asks for the
+// passphrase on the terminal if there is none. A new passphrase is asked for
+// twice to confirm it.
+func cliKeyring(config Config, confirm bool) (*keyring, error) {
+	if config.Secrets.Identity != "" {
+		return identityKeyring(config.Secrets.Identity)
+	}
+	tty := os.Stdin
+	if !isatty.IsTerminal(tty.Fd()) {
+		// The snippet may be piped in, so the passphrase is read from the
+		// terminal itself.
+		f, err := os.Open("/dev/tty")
+		if err != nil {
+			return nil, fmt.Errorf("%w, configure an identity file or run on a terminal", errLocked)
+		}
+		defer f.Close()
+		tty = f
+	}
+	readPassphrase := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(int(tty.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(b), err
+	}
+	passphrase, err := readPassphrase("Passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if confirm {
+		again, err := readPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if again != passphrase {
+			return nil, errors.New("passphrases do not match")
+		}
+	}
+	return passphraseKeyring(passphrase)
+}
+
+// decrypt returns the decrypted contents of the file.
+func (k *keyring) decrypt(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	k.mu.Lock()
+	d, ok := k.cache[path]
+	k.mu.Unlock()
+	if ok && d.modTime.Equal(info.ModTime()) {
+		return d.content, nil
+	}
+
+	ciphertext, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	r, err := agecrypt.Decrypt(bytes.NewReader(ciphertext), k.identities...)
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", filepath.Base(path), err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("decrypt %s: %w", filepath.Base(path), err)
+	}
+	k.mu.Lock()
+	k.cache[path] = decrypted{info.ModTime(), string(b)}
+	k.mu.Unlock()
+	return string(b), nil
+}
+
+// encrypt writes the content to the file, encrypted.
+func (k *keyring) encrypt(path, content string) error {
+	if len(k.recipients) <= 0 {
+		return errors.New("the identity file has no identities to encrypt to")
+	}
+	var b bytes.Buffer
+	w, err := agecrypt.Encrypt(&b, k.recipients...)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0600); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.cache, path)
+	if info, err := os.Stat(path); err == nil {
+		k.cache[path] = decrypted{info.ModTime(), content}
+	}
+	return nil
+}
+
+// readSnippetFile returns the contents of the snippet file, decrypted with
+// the keyring if the snippet is encrypted.
+func readSnippetFile(config Config, s Snippet, k *keyring) (string, error) {
+	path := filepath.Join(config.Root, s.Folder, s.File)
+	if !s.Encrypted {
+		content, err := os.ReadFile(path)
+		return string(content), err
+	}
+	if k == nil {
+		return "", errLocked
+	}
+	return k.decrypt(path)
+}
+
+// writeSnippetFile writes the contents of the snippet file, encrypted with
+// the keyring if the snippet is encrypted.
+func writeSnippetFile(config Config, s Snippet, k *keyring, content string) error {
+	path := filepath.Join(config.Root, s.Folder, s.File)
+	if !s.Encrypted {
+		return os.WriteFile(path, []byte(content), 0644)
+	}
+	if k == nil {
+		return errLocked
+	}
+	return k.encrypt(path, content)
+}
+
+// readSnippet returns the contents of the snippet, decrypting them if it is
+// encrypted.
+func (m *Model) readSnippet(s Snippet) (string, error) {
+	return readSnippetFile(m.config, s, m.keyring)
+}
+
+// decryptedMsg tells the application that an encrypted snippet was
+// decrypted.
+type decryptedMsg struct {
+	snippet Snippet
+	content string
+	err     error
+}
+
+// decryptSnippet returns a Cmd that decrypts the snippet, which takes a while
+// with a passphrase and so is kept out of Update.
+func (m *Model) decryptSnippet(s Snippet) tea.Cmd {
+	config, k := m.config, m.keyring
+	return func() tea.Msg {
+		content, err := readSnippetFile(config, s, k)
+		return decryptedMsg{s, content, err}
+	}
+}
+
+// writeSnippet writes the contents of the snippet, encrypting them if it is
+// encrypted.
+func (m *Model) writeSnippet(s Snippet, content string) error {
+	return writeSnippetFile(m.config, s, m.keyring, content)
+}
+
+// appendSnippet appends the content to the snippet, creating it if needed.
+func (m *Model) appendSnippet(s Snippet, content string) error {
+	if !s.Encrypted {
+		return appendFile(filepath.Join(m.config.Root, s.Folder, s.File), content)
+	}
+	existing, err := m.readSnippet(s)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return m.writeSnippet(s, existing+content)
+}
+
+// pendingUnlock is the unlocking of the encrypted snippets that waits for
+// the passphrase, and the action that continues once they are unlocked.
+type pendingUnlock struct {
+	// passphrase is the new passphrase that is being confirmed.
+	passphrase string
+	then       func() tea.Cmd
+}
+
+// unlock unlocks the encrypted snippets for the session and continues with
+// the action. The identity file unlocks them right away, otherwise the
+// passphrase is asked for.
+func (m *Model) unlock(then func() tea.Cmd) tea.Cmd {
+	if m.keyring != nil {
+		return then()
+	}
+	if m.config.Secrets.Identity != "" {
+		k, err := identityKeyring(m.config.Secrets.Identity)
+		if err != nil {
+			m.displayError(err.Error())
+			return nil
+		}
+		m.keyring = k
+		return then()
+	}
+	m.unlocking = &pendingUnlock{then: then}
+	m.state = unlockingState
+	m.inputs[passphraseInput].SetValue("")
+	m.updateKeyMap()
+	return m.focusInput(passphraseInput)
+}
+
+// encryptedSnippet returns an encrypted snippet to check passphrases
+// against, preferring the selected snippet.
+func (m *Model) encryptedSnippet() (Snippet, bool) {
+	if s := m.selectedSnippet(); s.Encrypted {
+		return s, true
+	}
+	for _, l := range m.Lists {
+		for _, item := range l.Items() {
+			if s, ok := item.(Snippet); ok && s.Encrypted {
+				return s, true
+			}
+		}
+	}
+	return Snippet{}, false
+}
+
+// passphraseCheckedMsg tells the application whether the keyring of the
+// passphrase that was entered decrypts the encrypted snippets.
+type passphraseCheckedMsg struct {
+	keyring *keyring
+	err     error
+}
+
+// submitPassphrase unlocks the encrypted snippets with the passphrase that
+// was entered, if it decrypts them. Without encrypted snippets to check it
+// against, a new passphrase is entered twice.
+//
+// The passphrase is checked by a Cmd, as decrypting with it takes a while.
+func (m *Model) submitPassphrase() tea.Cmd {
+	passphrase := m.inputs[passphraseInput].Value()
+	if passphrase == "" {
+		return nil
+	}
+	u := m.unlocking
+	k, err := passphraseKeyring(passphrase)
+	if err != nil {
+		m.stopUnlocking()
+		m.displayError(err.Error())
+		return nil
+	}
+
+	if s, ok := m.encryptedSnippet(); ok {
+		config := m.config
+		return func() tea.Msg {
+			_, err := readSnippetFile(config, s, k)
+			return passphraseCheckedMsg{k, err}
+		}
+	} else if u.passphrase == "" {
+		u.passphrase = passphrase
+		m.inputs[passphraseInput].SetValue("")
+		return nil
+	} else if u.passphrase != passphrase {
+		m.stopUnlocking()
+		m.displayError("Passphrases do not match.")
+		return nil
+	}
+
+	m.keyring = k
+	m.stopUnlocking()
+	return u.then()
+}
+
+// finishUnlocking unlocks the encrypted snippets with the keyring of the
+// passphrase if it was checked to decrypt them, unless the unlocking was
+// cancelled in the meantime.
+func (m *Model) finishUnlocking(msg passphraseCheckedMsg) tea.Cmd {
+	u := m.unlocking
+	if u == nil {
+		return nil
+	}
+	m.stopUnlocking()
+	if msg.err != nil {
+		m.displayError("Wrong passphrase.")
+		return nil
+	}
+	m.keyring = msg.keyring
+	return u.then()
+}
+
+// stopUnlocking closes the passphrase prompt.
+func (m *Model) stopUnlocking() {
+	m.unlocking = nil
+	m.state = navigatingState
+	m.inputs[passphraseInput].SetValue("")
+	m.blurInputs()
+	m.updateKeyMap()
+}
+
+// updateUnlock handles the key message while asking for the passphrase.
+func (m *Model) updateUnlock(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "esc":
+		m.stopUnlocking()
+		return m.updateContent()
+	case "enter":
+		return m.submitPassphrase()
+	}
+	var cmd tea.Cmd
+	m.inputs[passphraseInput], cmd = m.inputs[passphraseInput].Update(msg)
+	return cmd
+}
+
+// unlockLabel returns the label of the passphrase prompt.
+func (m *Model) unlockLabel() string {
+	if m.unlocking != nil && m.unlocking.passphrase != "" {
+		return "Confirm passphrase: "
+	}
+	return "Passphrase: "
+}
+
+// toggleEncryption encrypts the selected snippet, or decrypts it if it is
+// encrypted, unlocking the encrypted snippets first.
+func (m *Model) toggleEncryption() tea.Cmd {
+	s := m.selectedSnippet()
+	return m.unlock(func() tea.Cmd {
+		content, err := m.readSnippet(s)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			m.displayError(err.Error())
+			return nil
+		}
+		file := s.File + encryptedExt
+		if s.Encrypted {
+			file = strings.TrimSuffix(s.File, encryptedExt)
+		}
+		if _, err := os.Stat(filepath.Join(m.config.Root, s.Folder, file)); err == nil {
+			m.displayError(fmt.Sprintf("%s: %s/%s", errSnippetExists, s.Folder, file))
+			return nil
+		}
+		toggled := newSnippet(s.Folder, file)
+		toggled.Tags = s.Tags
+		toggled.Sensitive = s.Sensitive
+		if err := m.writeSnippet(toggled, content); err != nil {
+			m.displayError(err.Error())
+			return nil
+		}
+		if err := os.Remove(filepath.Join(m.config.Root, s.Folder, s.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			m.displayError(err.Error())
+			return nil
+		}
+		err = updateLibrary(m.config.Root, func(lib Library) {
+			lib.move(s.Folder, s.File, s.Folder, file)
+		})
+		if err != nil {
+			m.displayError(err.Error())
+			return nil
+		}
+		m.updateKeyMap()
+		return tea.Batch(m.replaceSnippet(s, toggled), m.updateContent())
+	})
+}
+
+// lockedHints returns the hints shown in place of an encrypted snippet while
+// the encrypted snippets are locked.
+func (m *Model) lockedHints() []keyHint {
+	return []keyHint{
+		{m.keys.Unlock, "unlock encrypted snippets."},
+	}
+}
+
+// isLocked reports whether the snippet is encrypted and cannot be read until
+// the encrypted snippets are unlocked.
+func (m *Model) isLocked(s Snippet) bool {
+	return s.Encrypted && m.keyring == nil
+}
+
+// unlockFor continues with the action, unlocking the encrypted snippets first
+// if any of the snippets is encrypted.
+func (m *Model) unlockFor(snippets []Snippet, then func() tea.Cmd) tea.Cmd {
+	for _, s := range snippets {
+		if m.isLocked(s) {
+			return m.unlock(then)
+		}
+	}
+	return then()
+}
+
+// plaintextDir returns the directory that decrypted copies of snippets are
+// written to: $XDG_RUNTIME_DIR or /dev/shm, which are kept in memory on most
+// systems, or else the temporary directory, which may be on disk.
+func plaintextDir() string {
+	for _, dir := range []string{os.Getenv("XDG_RUNTIME_DIR"), "/dev/shm"} {
+		if fi, err := os.Stat(dir); dir != "" && err == nil && fi.IsDir() {
+			return dir
+		}
+	}
+	return os.TempDir()
+}
+
+// editEncryptedSnippet opens the editor with a decrypted copy of the selected
+// snippet in a private temporary directory of plaintextDir. The copy is
+// encrypted back into the snippet when the editor exits and then removed.
+// Swap and backup files that the editor writes elsewhere are not removed.
+func (m *Model) editEncryptedSnippet() tea.Cmd {
+	s := m.selectedSnippet()
+	content, err := m.readSnippet(s)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		m.displayError(err.Error())
+		return nil
+	}
+	dir, err := os.MkdirTemp(plaintextDir(), "snp-edit-")
+	if err != nil {
+		m.displayError(err.Error())
+		return nil
+	}
+	path := filepath.Join(dir, s.Name+"."+s.Language)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		os.RemoveAll(dir)
+		m.displayError(err.Error())
+		return nil
+	}
+	_ = recordUse(m.config.StateDir, s)
+	return m.openEditor(s, path, func(err error) error {
+		defer os.RemoveAll(dir)
+		if err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(path)
+		if err != nil || string(edited) == content {
+			return err
+		}
+		return m.writeSnippet(s, string(edited))
+	})
+}
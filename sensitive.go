@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultClearAfter is how long sensitive snippets stay on the clipboard
+// unless configured otherwise.
+const defaultClearAfter = 30 * time.Second
+
+// mask is shown in place of the lines of sensitive snippets until they are
+// revealed.
+const mask = "••••••••"
+
+// isSensitive reports whether the snippet is sensitive by its metadata, tags
+// or folder.
+func (c Config) isSensitive(s Snippet) bool {
+	if s.Sensitive || slices.Contains(c.Sensitive.Folders, s.Folder) {
+		return true
+	}
+	for _, tag := range s.Tags {
+		if slices.Contains(c.Sensitive.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskContent returns the content with every line that is not blank masked,
+// hiding the length of the lines as well.
+func maskContent(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = mask
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sensitiveCopiesSize is the number of copies of sensitive snippets that are
+// remembered.
+const sensitiveCopiesSize = 50
+
+// sensitiveCopy is a copy of sensitive snippets to the clipboard, known by the
+// hash of its contents so that the contents are not kept.
+type sensitiveCopy struct {
+	Hash string    `yaml:"hash"`
+	Time time.Time `yaml:"time"`
+}
+
+// sensitiveCopies are the recent copies of sensitive snippets, newest first.
+// They are kept in the state directory, so that every snp process keeps their
+// contents out of the clipboard history, whether it can read the snippets or
+// not.
+type sensitiveCopies []sensitiveCopy
+
+// sensitiveCopiesMu serializes the updates of the copies of sensitive
+// snippets.
+var sensitiveCopiesMu sync.Mutex
+
+// sensitiveCopiesFile returns the path of the copies of sensitive snippets in
+// the state directory.
+func sensitiveCopiesFile(stateDir string) string {
+	return filepath.Join(stateDir, "sensitive.yaml")
+}
+
+// contentHash returns the hash that copies of the content are known by.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// readSensitiveCopies reads the copies of sensitive snippets from the state
+// directory.
+func readSensitiveCopies(stateDir string) (sensitiveCopies, error) {
+	var copies sensitiveCopies
+	content, err := os.ReadFile(sensitiveCopiesFile(stateDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return copies, nil
+	}
+	if err != nil {
+		return copies, err
+	}
+	return copies, yaml.Unmarshal(content, &copies)
+}
+
+// recordSensitiveCopy remembers a copy of the content in the state directory.
+func recordSensitiveCopy(stateDir, content string) error {
+	sensitiveCopiesMu.Lock()
+	defer sensitiveCopiesMu.Unlock()
+
+	copies, err := readSensitiveCopies(stateDir)
+	if err != nil {
+		return err
+	}
+	c := sensitiveCopy{Hash: contentHash(content), Time: time.Now()}
+	recent := sensitiveCopies{c}
+	for _, old := range copies {
+		if old.Hash != c.Hash && len(recent) < sensitiveCopiesSize {
+			recent = append(recent, old)
+		}
+	}
+	b, err := yaml.Marshal(recent)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	return replaceFile(sensitiveCopiesFile(stateDir), b, 0600)
+}
+
+// find returns the latest copy of the content with the hash.
+func (copies sensitiveCopies) find(hash string) (sensitiveCopy, bool) {
+	for _, c := range copies {
+		if c.Hash == hash {
+			return c, true
+		}
+	}
+	return sensitiveCopy{}, false
+}
+
+// isSensitiveCopy reports whether the content was copied from sensitive
+// snippets, which keeps it out of the clipboard history.
+func isSensitiveCopy(stateDir, content string) bool {
+	if strings.TrimSpace(content) == "" {
+		return false
+	}
+	copies, err := readSensitiveCopies(stateDir)
+	if err != nil {
+		// Better to miss a capture than to keep a secret.
+		return true
+	}
+	_, ok := copies.find(contentHash(content))
+	return ok
+}
+
+// sensitiveClipboard is implemented by clipboards that keep copies of
+// sensitive snippets apart from other copies, to remove them from there
+// rather than overwrite them.
+type sensitiveClipboard interface {
+	WriteSensitive(text string) error
+	// SensitiveHash returns the hash of the copy of sensitive snippets on
+	// the clipboard, or nothing if there is none.
+	SensitiveHash() (string, error)
+	ClearSensitive() error
+}
+
+// copySensitive copies the contents of sensitive snippets to the clipboard,
+// remembering the copy first so that it never makes it into the history.
+func copySensitive(cb Clipboard, stateDir, content string) error {
+	if err := recordSensitiveCopy(stateDir, content); err != nil {
+		return err
+	}
+	if s, ok := cb.(sensitiveClipboard); ok {
+		return s.WriteSensitive(content)
+	}
+	return cb.WriteAll(content)
+}
+
+// clearSensitiveClipboard clears the clipboard if it holds a copy of sensitive
+// snippets that was made at least the duration ago. The clipboard is left
+// alone if something else was copied since, or if the same contents were
+// copied again later, which are cleared after their own timeout.
+func clearSensitiveClipboard(cb Clipboard, stateDir string, after time.Duration) error {
+	var hash string
+	s, separate := cb.(sensitiveClipboard)
+	if separate {
+		var err error
+		if hash, err = s.SensitiveHash(); err != nil || hash == "" {
+			return err
+		}
+	} else {
+		current, err := cb.ReadAll()
+		if err != nil || strings.TrimSpace(current) == "" {
+			return err
+		}
+		hash = contentHash(current)
+	}
+	copies, err := readSensitiveCopies(stateDir)
+	if err != nil {
+		return err
+	}
+	if c, ok := copies.find(hash); !ok || time.Since(c.Time) < after {
+		return nil
+	}
+	if separate {
+		return s.ClearSensitive()
+	}
+	return cb.WriteAll("")
+}
+
+// startClipboardClearer starts a snp process of its own that clears the
+// copy of sensitive snippets from the clipboard after the duration, so that
+// they are cleared even when the application exits before.
+var startClipboardClearer = func(after time.Duration) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, "clip", "--clear-after", after.String())
+	// The terminal clipboard is cleared through the terminal.
+	cmd.Stderr = os.Stderr
+	detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// sensitiveCopiedMsg tells the application that the contents of sensitive
+// snippets were copied to the clipboard, and that it has to clear them
+// itself.
+type sensitiveCopiedMsg struct{}
+
+// clearClipboardMsg tells the application to clear the clipboard if it still
+// holds the contents of sensitive snippets.
+type clearClipboardMsg struct{}
+
+// clearClipboardAfter returns a Cmd that clears the clipboard once the
+// contents of sensitive snippets were on it for the configured time.
+func (m *Model) clearClipboardAfter() tea.Cmd {
+	m.clearingClipboard = true
+	return tick(m.config.Sensitive.clearAfter(), func(time.Time) tea.Msg {
+		return clearClipboardMsg{}
+	})
+}
+
+// clearClipboard clears the clipboard if it still holds the contents of
+// sensitive snippets that were copied at least the duration ago.
+func (m *Model) clearClipboard(after time.Duration) error {
+	m.clearingClipboard = false
+	return clearSensitiveClipboard(m.clipboard, m.config.StateDir, after)
+}
+
+// masked reports whether the previewed snippet is sensitive and not
+// revealed.
+func (m *Model) masked() bool {
+	return m.config.isSensitive(m.previewed) && !m.revealed
+}
+
+// toggleReveal reveals the previewed sensitive snippet, or masks it again.
+func (m *Model) toggleReveal() {
+	m.revealed = !m.revealed
+	if err := m.highlightContent(); err != nil {
+		m.displayError("Unable to highlight file.")
+	}
+	m.updateKeyMap()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// separateClipboard keeps sensitive copies apart from other copies, as the
+// tmux clipboard does.
+type separateClipboard struct {
+	localClipboard
+	sensitive *string
+}
+
+func (c separateClipboard) WriteSensitive(text string) error { *c.sensitive = text; return nil }
+func (c separateClipboard) ClearSensitive() error            { *c.sensitive = ""; return nil }
+
+func (c separateClipboard) SensitiveHash() (string, error) {
+	if *c.sensitive == "" {
+		return "", nil
+	}
+	return contentHash(*c.sensitive), nil
+}
+
+func TestCaptureSensitiveCopy(t *testing.T) {
+	stateDir := t.TempDir()
+	cb := localClipboard{filepath.Join(stateDir, "clipboard")}
+
+	if err := copySensitive(cb, stateDir, "hunter2\n"); err != nil {
+		t.Fatal(err)
+	}
+	h, err := captureClipboard(cb, stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 0 {
+		t.Errorf("captured the sensitive copy: %v", h)
+	}
+
+	if err := cb.WriteAll("ls -la\n"); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = captureClipboard(cb, stateDir); err != nil || len(h) != 1 {
+		t.Errorf("got %v, %v, want the copy in the history", h, err)
+	}
+}
+
+func TestClearSensitiveClipboard(t *testing.T) {
+	tests := []struct {
+		name string
+		// copied is copied to the clipboard after the sensitive copy.
+		copied string
+		after  time.Duration
+		want   string
+	}{
+		{"timed out", "", 0, ""},
+		{"not timed out", "", time.Hour, "hunter2\n"},
+		{"copied since", "ls -la\n", 0, "ls -la\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateDir := t.TempDir()
+			cb := localClipboard{filepath.Join(stateDir, "clipboard")}
+			if err := copySensitive(cb, stateDir, "hunter2\n"); err != nil {
+				t.Fatal(err)
+			}
+			if tt.copied != "" {
+				if err := cb.WriteAll(tt.copied); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := clearSensitiveClipboard(cb, stateDir, tt.after); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := cb.ReadAll(); got != tt.want {
+				t.Errorf("got %q on the clipboard, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Clipboards that keep sensitive copies apart clear them whatever was
+	// copied since.
+	stateDir := t.TempDir()
+	sensitive := ""
+	cb := separateClipboard{localClipboard{filepath.Join(stateDir, "clipboard")}, &sensitive}
+	if err := copySensitive(cb, stateDir, "hunter2\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cb.WriteAll("ls -la\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := clearSensitiveClipboard(cb, stateDir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := cb.ReadAll(); sensitive != "" || got != "ls -la\n" {
+		t.Errorf("got %q as the sensitive copy and %q on the clipboard", sensitive, got)
+	}
+}
+
+func TestSensitiveCopyFiles(t *testing.T) {
+	stateDir := t.TempDir()
+	var out bytes.Buffer
+	terminal := terminalClipboard{&out, localClipboard{clipboardFile(stateDir)}}
+	if err := terminal.WriteAll("ls -la\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := copySensitive(terminal, stateDir, "hunter2\n"); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() == 0 {
+		t.Error("nothing was sent to the terminal")
+	}
+	// The terminal clipboard keeps no plaintext copy of sensitive snippets
+	// to paste from.
+	if got, _ := terminal.ReadAll(); got != "" {
+		t.Errorf("got %q in the file of the terminal clipboard", got)
+	}
+	if err := clearSensitiveClipboard(terminal, stateDir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if hash, _ := terminal.SensitiveHash(); hash != "" {
+		t.Errorf("the copy was not cleared, got hash %s", hash)
+	}
+
+	// The file clipboard removes its file when the copy is cleared.
+	file := localClipboard{clipboardFile(stateDir)}
+	if err := copySensitive(file, stateDir, "hunter2\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := clearSensitiveClipboard(file, stateDir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file.path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the file clipboard was left behind: %v", err)
+	}
+}
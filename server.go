@@ -0,0 +1,506 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sahilm/fuzzy"
+	"golang.org/x/exp/slices"
+)
+
+// defaultAddr is the address that the API is served on unless another
+// address or a Unix socket is given.
+const defaultAddr = "127.0.0.1:7878"
+
+// maxRequestSize limits the size of the request bodies of the API.
+const maxRequestSize = 4 << 20
+
+var errNoSnippet = errors.New("no such snippet")
+
+// apiSnippet is a snippet as returned by the API. The content is only
+// included when a single snippet is requested.
+type apiSnippet struct {
+	Folder       string            `json:"folder"`
+	Name         string            `json:"name"`
+	File         string            `json:"file"`
+	Language     string            `json:"language"`
+	Tags         []string          `json:"tags"`
+	Description  string            `json:"description,omitempty"`
+	Encrypted    bool              `json:"encrypted,omitempty"`
+	Sensitive    bool              `json:"sensitive,omitempty"`
+	Content      *string           `json:"content,omitempty"`
+	Placeholders map[string]string `json:"placeholders,omitempty"`
+}
+
+// apiSnippetRequest is the body of requests that create or update a
+// snippet. Updates only change the content, tags and description, and keep
+// the ones that are left out as they are.
+type apiSnippetRequest struct {
+	Folder      string    `json:"folder"`
+	Name        string    `json:"name"`
+	Language    string    `json:"language"`
+	Content     *string   `json:"content"`
+	Tags        *[]string `json:"tags"`
+	Description *string   `json:"description"`
+}
+
+// apiRenderRequest is the body of requests that render a snippet with the
+// values of its placeholders.
+type apiRenderRequest struct {
+	Values map[string]string `json:"values"`
+}
+
+// apiError is an error that is responded with the HTTP status.
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e apiError) Error() string { return e.err.Error() }
+
+func (e apiError) Unwrap() error { return e.err }
+
+// statusError returns the error that is responded with the HTTP status.
+func statusError(status int, err error) error {
+	return apiError{status, err}
+}
+
+// apiServer serves the JSON API over the snippets in the snippet root. The
+// snippets are read again for every request so that changes made by the
+// interactive mode or the command line are picked up.
+type apiServer struct {
+	config Config
+}
+
+// newAPIServer returns the handler of the JSON API:
+//
+//	GET    /snippets[?folder=&tag=&sort=]     list the snippets
+//	POST   /snippets                          create a snippet
+//	GET    /search?q=                         search the names and contents
+//	GET    /snippets/{folder}/{file}[?format=raw|html]
+//	PUT    /snippets/{folder}/{file}          update a snippet
+//	DELETE /snippets/{folder}/{file}          move a snippet to the trash
+//	POST   /snippets/{folder}/{file}/render   fill in the placeholders
+func newAPIServer(config Config) http.Handler {
+	s := &apiServer{config}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/snippets", s.handle(s.snippets))
+	mux.HandleFunc("/snippets/", s.handle(s.snippet))
+	mux.HandleFunc("/search", s.handle(s.search))
+	return mux
+}
+
+// handle returns a handler that responds with the error of the API handler,
+// as JSON with the status of the error.
+//
+// Requests must be made to a loopback host or over a Unix socket, so that web
+// pages cannot reach the API by pointing their domains at the loopback
+// address, and requests that change snippets must be JSON, which browsers
+// only send to other origins when the API allows it.
+func (s *apiServer) handle(h func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		var err error
+		switch {
+		case !loopbackRequest(r):
+			err = statusError(http.StatusForbidden, fmt.Errorf("host not allowed: %s", r.Host))
+		case (r.Method == http.MethodPost || r.Method == http.MethodPut) && !jsonRequest(r):
+			err = statusError(http.StatusUnsupportedMediaType, errors.New("content type must be application/json"))
+		default:
+			err = h(w, r)
+		}
+		if err == nil {
+			return
+		}
+		status := http.StatusInternalServerError
+		var apiErr apiError
+		switch {
+		case errors.As(err, &apiErr):
+			status = apiErr.status
+		case errors.Is(err, errNoSnippet), errors.Is(err, fs.ErrNotExist):
+			status = http.StatusNotFound
+		case errors.Is(err, errSnippetExists):
+			status = http.StatusConflict
+		case errors.Is(err, errInvalidFolder):
+			status = http.StatusBadRequest
+		case errors.Is(err, errLocked):
+			status = http.StatusForbidden
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+	}
+}
+
+// loopbackRequest reports whether the request was made over a Unix socket or
+// to a loopback host.
+func loopbackRequest(r *http.Request) bool {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+		return true
+	}
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// jsonRequest reports whether the body of the request is JSON.
+func jsonRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// writeJSON responds with the value as JSON.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// readJSON decodes the body of the request into the value. An empty body
+// leaves the value as is.
+func readJSON(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return statusError(http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+	}
+	return nil
+}
+
+// methodNotAllowed returns the error for requests with a method other than
+// the allowed ones.
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) error {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	return statusError(http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+// newAPISnippet returns the snippet as returned by the API, with the
+// description from the library.
+func (s *apiServer) newAPISnippet(snippet Snippet, lib Library) apiSnippet {
+	tags := snippet.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return apiSnippet{
+		Folder:      snippet.Folder,
+		Name:        snippet.Name,
+		File:        snippet.File,
+		Language:    snippet.Language,
+		Tags:        tags,
+		Description: lib[metadataKey(snippet.Folder, snippet.File)].Description,
+		Encrypted:   snippet.Encrypted,
+		Sensitive:   s.config.isSensitive(snippet),
+	}
+}
+
+// apiSnippets returns the snippets as returned by the API.
+func (s *apiServer) apiSnippets(snippets []Snippet) []apiSnippet {
+	lib, _ := readLibrary(s.config.Root)
+	result := make([]apiSnippet, 0, len(snippets))
+	for _, snippet := range snippets {
+		result = append(result, s.newAPISnippet(snippet, lib))
+	}
+	return result
+}
+
+// snippets lists the snippets, or creates a snippet.
+func (s *apiServer) snippets(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case http.MethodGet:
+		return s.list(w, r)
+	case http.MethodPost:
+		return s.create(w, r)
+	}
+	return methodNotAllowed(w, http.MethodGet, http.MethodPost)
+}
+
+// list responds with the snippets of the folder and with the tag if given,
+// sorted by the sort mode or by folder and name.
+func (s *apiServer) list(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	mode := nameSort
+	if name := query.Get("sort"); name != "" {
+		var err error
+		if mode, err = parseSort(name); err != nil {
+			return statusError(http.StatusBadRequest, err)
+		}
+	}
+	var snippets []Snippet
+	for _, snippet := range readSnippets(s.config) {
+		if folder := query.Get("folder"); folder != "" && snippet.Folder != folder {
+			continue
+		}
+		if tag := query.Get("tag"); tag != "" && !slices.Contains(snippet.Tags, tag) {
+			continue
+		}
+		snippets = append(snippets, snippet)
+	}
+	sortSnippets(snippets, mode, s.config)
+	if mode == nameSort {
+		slices.SortStableFunc(snippets, func(a, b Snippet) bool { return a.Folder < b.Folder })
+	}
+	writeJSON(w, http.StatusOK, s.apiSnippets(snippets))
+	return nil
+}
+
+// search responds with the snippets whose names fuzzy match the query,
+// best matches first, followed by the snippets whose contents hold it.
+// The contents of encrypted and sensitive snippets are not searched.
+func (s *apiServer) search(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet {
+		return methodNotAllowed(w, http.MethodGet)
+	}
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		return statusError(http.StatusBadRequest, errors.New("missing query"))
+	}
+
+	snippets := readSnippets(s.config)
+	var (
+		results []Snippet
+		found   = map[int]bool{}
+	)
+	for _, match := range fuzzy.FindFrom(query, Snippets{snippets}) {
+		results = append(results, snippets[match.Index])
+		found[match.Index] = true
+	}
+	lower := strings.ToLower(query)
+	for i, snippet := range snippets {
+		if found[i] || snippet.Encrypted || s.config.isSensitive(snippet) {
+			continue
+		}
+		content, err := readSnippetFile(s.config, snippet, nil)
+		if err == nil && strings.Contains(strings.ToLower(content), lower) {
+			results = append(results, snippet)
+		}
+	}
+	writeJSON(w, http.StatusOK, s.apiSnippets(results))
+	return nil
+}
+
+// create creates a snippet. The language is detected from the content if
+// not given, and the name defaults to a name that is not taken yet.
+func (s *apiServer) create(w http.ResponseWriter, r *http.Request) error {
+	var req apiSnippetRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	if req.Folder == "" {
+		req.Folder = defaultSnippetFolder
+	}
+	if err := validFolder(req.Folder); err != nil {
+		return err
+	}
+	var content string
+	if req.Content != nil {
+		content = *req.Content
+	}
+	if req.Language == "" {
+		req.Language = detectLanguage(content, "", s.config.DefaultLanguage)
+	}
+
+	var file string
+	if req.Name == "" {
+		file = uniqueFile(s.config.Root, req.Folder, Snippet{}.fileName(defaultSnippetName, req.Language))
+	} else {
+		file = Snippet{}.fileName(req.Name, req.Language)
+	}
+	if strings.ContainsAny(file, `/\`) || ignoredFile(file) || strings.HasSuffix(file, encryptedExt) {
+		return statusError(http.StatusBadRequest, fmt.Errorf("invalid snippet name: %q", file))
+	}
+	if err := os.MkdirAll(filepath.Join(s.config.Root, req.Folder), 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(s.config.Root, req.Folder, file)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%w: %s/%s", errSnippetExists, req.Folder, file)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.WriteString(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	snippet := newSnippet(req.Folder, file)
+	if err := s.updateMetadata(&snippet, req); err != nil {
+		return err
+	}
+	return s.respondSnippet(w, http.StatusCreated, snippet, content)
+}
+
+// updateMetadata stores the tags and description of the request for the
+// snippet.
+func (s *apiServer) updateMetadata(snippet *Snippet, req apiSnippetRequest) error {
+	if req.Tags == nil && req.Description == nil {
+		return nil
+	}
+	return updateLibrary(s.config.Root, func(lib Library) {
+		key := metadataKey(snippet.Folder, snippet.File)
+		md := lib[key]
+		if req.Tags != nil {
+			md.Tags = addTags(nil, *req.Tags)
+		}
+		if req.Description != nil {
+			md.Description = *req.Description
+		}
+		if md.empty() {
+			delete(lib, key)
+		} else {
+			lib[key] = md
+		}
+		snippet.Tags = md.Tags
+	})
+}
+
+// respondSnippet responds with the snippet, including its content and the
+// default values of its placeholders.
+func (s *apiServer) respondSnippet(w http.ResponseWriter, status int, snippet Snippet, content string) error {
+	lib, err := readLibrary(s.config.Root)
+	if err != nil {
+		return err
+	}
+	result := s.newAPISnippet(snippet, lib)
+	result.Content = &content
+	if values := placeholders(content); len(values) > 0 {
+		result.Placeholders = map[string]string{}
+		for _, p := range values {
+			result.Placeholders[p.name] = p.value
+		}
+	}
+	writeJSON(w, status, result)
+	return nil
+}
+
+// snippet handles the requests for a single snippet, given by its folder
+// and file name.
+func (s *apiServer) snippet(w http.ResponseWriter, r *http.Request) error {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/snippets/"), "/")
+	render := len(parts) == 3 && parts[2] == "render"
+	if len(parts) != 2 && !render {
+		return statusError(http.StatusNotFound, errors.New("not found"))
+	}
+	snippet, err := s.resolve(parts[0], parts[1])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case render && r.Method == http.MethodPost:
+		return s.render(w, r, snippet)
+	case render:
+		return methodNotAllowed(w, http.MethodPost)
+	case r.Method == http.MethodGet:
+		return s.get(w, r, snippet)
+	case r.Method == http.MethodPut:
+		return s.update(w, r, snippet)
+	case r.Method == http.MethodDelete:
+		if err := trashSnippetFile(s.config.Root, snippet); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	return methodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+}
+
+// resolve returns the snippet of the folder with the file name, or with the
+// name.ext of the snippet.
+func (s *apiServer) resolve(folder, file string) (Snippet, error) {
+	for _, snippet := range readSnippets(s.config) {
+		if snippet.Folder == folder && (snippet.File == file || snippet.Name+"."+snippet.Language == file) {
+			return snippet, nil
+		}
+	}
+	return Snippet{}, fmt.Errorf("%w: %s/%s", errNoSnippet, folder, file)
+}
+
+// get responds with the snippet as JSON, or with its content as is or
+// highlighted as HTML.
+func (s *apiServer) get(w http.ResponseWriter, r *http.Request, snippet Snippet) error {
+	content, err := readSnippetFile(s.config, snippet, nil)
+	if err != nil {
+		return err
+	}
+	_ = recordUse(s.config.StateDir, snippet)
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		return s.respondSnippet(w, http.StatusOK, snippet, content)
+	case "raw":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, err = fmt.Fprint(w, content)
+		return err
+	case "html":
+		highlighted, err := highlightHTML(content, snippet.Language, s.config)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, err = fmt.Fprint(w, highlighted)
+		return err
+	default:
+		return statusError(http.StatusBadRequest, fmt.Errorf("unknown format %q, use json, raw or html", format))
+	}
+}
+
+// update replaces the content, tags or description of the snippet with the
+// ones given.
+func (s *apiServer) update(w http.ResponseWriter, r *http.Request, snippet Snippet) error {
+	var req apiSnippetRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	content, err := readSnippetFile(s.config, snippet, nil)
+	if err != nil {
+		return err
+	}
+	if req.Content != nil {
+		content = *req.Content
+		if err := writeSnippetFile(s.config, snippet, nil, content); err != nil {
+			return err
+		}
+	}
+	if err := s.updateMetadata(&snippet, req); err != nil {
+		return err
+	}
+	return s.respondSnippet(w, http.StatusOK, snippet, content)
+}
+
+// render responds with the content of the snippet with its placeholders
+// filled in with the values given, or else with their default values.
+func (s *apiServer) render(w http.ResponseWriter, r *http.Request, snippet Snippet) error {
+	var req apiRenderRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	content, err := readSnippetFile(s.config, snippet, nil)
+	if err != nil {
+		return err
+	}
+	values := placeholders(content)
+	for i, p := range values {
+		if v, ok := req.Values[p.name]; ok {
+			values[i].value = v
+		}
+	}
+	_ = recordUse(s.config.StateDir, snippet)
+	writeJSON(w, http.StatusOK, map[string]string{"content": fillPlaceholders(content, values)})
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	config, _ := testSnippets(t, map[string]string{
+		"shell/greet.sh":      "echo {{greeting:hello}} {{name}}\n",
+		"misc/secret.txt.age": "not really encrypted",
+	})
+	srv := httptest.NewServer(newAPIServer(config))
+	defer srv.Close()
+
+	tests := []struct {
+		name, method, path, body string
+		status                   int
+		want                     string
+	}{
+		{"list", "GET", "/snippets", "", http.StatusOK, `"file":"hello.go"`},
+		{"list folder", "GET", "/snippets?folder=notes", "", http.StatusOK, `[{"folder":"notes","name":"readme","file":"readme.txt","language":"txt","tags":[]}]`},
+		{"list bad sort", "GET", "/snippets?sort=nope", "", http.StatusBadRequest, `"error"`},
+		{"search name", "GET", "/search?q=hello", "", http.StatusOK, `"file":"hello.go"`},
+		{"search content", "GET", "/search?q=milk", "", http.StatusOK, `"file":"readme.txt"`},
+		{"get", "GET", "/snippets/shell/greet.sh", "", http.StatusOK, `"placeholders":{"greeting":"hello","name":""}`},
+		{"get raw", "GET", "/snippets/shell/list.sh?format=raw", "", http.StatusOK, "ls -la\n"},
+		{"get html", "GET", "/snippets/misc/hello.go?format=html", "", http.StatusOK, "<pre"},
+		{"get missing", "GET", "/snippets/misc/nope.go", "", http.StatusNotFound, `"error"`},
+		{"get encrypted", "GET", "/snippets/misc/secret.txt", "", http.StatusForbidden, `"error"`},
+		{"render", "POST", "/snippets/shell/greet.sh/render", `{"values":{"name":"world"}}`, http.StatusOK, `{"content":"echo hello world\n"}`},
+		{"create", "POST", "/snippets", `{"folder":"new","name":"up","content":"#!/bin/sh\nuptime\n","tags":["ops"]}`, http.StatusCreated, `"file":"up.sh","language":"sh","tags":["ops"]`},
+		{"create exists", "POST", "/snippets", `{"folder":"new","name":"up","language":"sh"}`, http.StatusConflict, `"error"`},
+		{"create invalid", "POST", "/snippets", `{"folder":"../up"}`, http.StatusBadRequest, `"error"`},
+		{"update", "PUT", "/snippets/new/up.sh", `{"content":"w\n","description":"who is up"}`, http.StatusOK, `"description":"who is up","content":"w\n"`},
+		{"list tag", "GET", "/snippets?tag=ops", "", http.StatusOK, `"file":"up.sh"`},
+		{"delete", "DELETE", "/snippets/new/up.sh", "", http.StatusNoContent, ""},
+		{"get deleted", "GET", "/snippets/new/up.sh", "", http.StatusNotFound, `"error"`},
+		{"method", "PATCH", "/snippets/misc/hello.go", "", http.StatusMethodNotAllowed, `"error"`},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, resp.StatusCode, tt.status, body)
+		}
+		if got := string(body); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: got %s, want it to contain %s", tt.name, got, tt.want)
+		}
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") && !json.Valid(body) {
+			t.Errorf("%s: invalid JSON: %s", tt.name, body)
+		}
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	config, _ := testSnippets(t, nil)
+	srv := httptest.NewServer(newAPIServer(config))
+	defer srv.Close()
+
+	tests := []struct {
+		name, method, host, contentType string
+		status                          int
+	}{
+		{"rebound host", "GET", "snippets.example.com", "", http.StatusForbidden},
+		{"rebound host with port", "GET", "snippets.example.com:7878", "", http.StatusForbidden},
+		{"localhost", "GET", "localhost:7878", "", http.StatusOK},
+		{"form", "POST", "", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"text", "POST", "", "text/plain", http.StatusUnsupportedMediaType},
+		{"no content type", "POST", "", "", http.StatusUnsupportedMediaType},
+		{"json", "POST", "", "application/json; charset=utf-8", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, srv.URL+"/snippets", strings.NewReader(`{"folder":"new","name":"up","language":"sh"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.host != "" {
+			req.Host = tt.host
+		}
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(config.Root, "new", "up.sh")); err != nil {
+		t.Errorf("the JSON request did not create the snippet: %v", err)
+	}
+}
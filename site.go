@@ -0,0 +1,297 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/styles"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// siteFiles holds the templates and assets of the static site export.
+//
+//go:embed site
+var siteFiles embed.FS
+
+// siteAssets are the files that are copied into the site as they are.
+var siteAssets = []string{"style.css", "site.js"}
+
+// siteManifest lists the files that the export wrote to the directory of the
+// site, so that the next export knows which of them it may remove.
+const siteManifest = ".snp-site"
+
+// errNotSite is returned when exporting a site into a directory that holds
+// files of its own.
+var errNotSite = errors.New("directory is not empty and holds no snp site")
+
+// siteSnippet is a snippet as shown on the pages of the site.
+type siteSnippet struct {
+	Snippet
+	Description string
+	// URL is the page of the snippet relative to the root of the site.
+	URL         string
+	Content     string
+	Highlighted template.HTML
+}
+
+// siteGroup is a folder or tag along with its snippets.
+type siteGroup struct {
+	Name     string
+	Snippets []*siteSnippet
+}
+
+// sitePage is the data of a page of the site. Root is the path from the page
+// to the root of the site.
+type sitePage struct {
+	Title      string
+	Root       string
+	Background template.CSS
+	Foreground template.CSS
+	Folders    []siteGroup
+	Tags       []siteGroup
+	Snippet    *siteSnippet
+}
+
+// siteIndexEntry is a snippet in the search index of the site.
+type siteIndexEntry struct {
+	Title       string   `json:"title"`
+	Folder      string   `json:"folder"`
+	Name        string   `json:"name"`
+	Language    string   `json:"language"`
+	Tags        []string `json:"tags"`
+	Description string   `json:"description"`
+	URL         string   `json:"url"`
+	Content     string   `json:"content"`
+}
+
+// exportSite renders the snippets as a static site in the directory: an
+// index by folder and tag, a highlighted page for every snippet and a search
+// index. Sensitive snippets are left out of the site, as are encrypted
+// snippets unless there is a keyring to decrypt them with.
+//
+// The directory must be empty or hold a site that was exported before. The
+// pages of the previous export that are not written again, such as those of
+// snippets that were removed, renamed or made sensitive since, are removed,
+// while files that the export did not write are left alone.
+//
+// It returns the number of snippets that were exported.
+func exportSite(config Config, snippets []Snippet, dir string, k *keyring) (int, error) {
+	tmpl, err := template.ParseFS(siteFiles, "site/*.html")
+	if err != nil {
+		return 0, err
+	}
+	previous, err := previousSite(dir)
+	if err != nil {
+		return 0, err
+	}
+	lib, err := readLibrary(config.Root)
+	if err != nil {
+		return 0, err
+	}
+
+	snippets = slices.Clone(snippets)
+	sortSnippets(snippets, nameSort, config)
+	var (
+		folders = map[string][]*siteSnippet{}
+		tags    = map[string][]*siteSnippet{}
+		index   = []siteIndexEntry{}
+		pages   []*siteSnippet
+	)
+	for _, s := range snippets {
+		if (s.Encrypted && k == nil) || config.isSensitive(s) {
+			continue
+		}
+		content, err := readSnippetFile(config, s, k)
+		if err != nil {
+			return 0, err
+		}
+		highlighted, err := highlightHTML(content, s.Language, config)
+		if err != nil {
+			return 0, err
+		}
+		page := &siteSnippet{
+			Snippet:     s,
+			Description: lib[metadataKey(s.Folder, s.File)].Description,
+			URL:         path.Join("snippets", url.PathEscape(s.Folder), url.PathEscape(s.File)+".html"),
+			Content:     content,
+			Highlighted: template.HTML(highlighted),
+		}
+		pages = append(pages, page)
+		folders[s.Folder] = append(folders[s.Folder], page)
+		for _, tag := range s.Tags {
+			tags[tag] = append(tags[tag], page)
+		}
+		entryTags := s.Tags
+		if entryTags == nil {
+			entryTags = []string{}
+		}
+		index = append(index, siteIndexEntry{
+			Title:       s.String(),
+			Folder:      s.Folder,
+			Name:        s.Name,
+			Language:    s.Language,
+			Tags:        entryTags,
+			Description: page.Description,
+			URL:         page.URL,
+			Content:     content,
+		})
+	}
+
+	background, foreground := siteColors(config)
+	written := map[string]bool{}
+	write := func(name, templateName string, data sitePage) error {
+		written[name] = true
+		name = filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		data.Background, data.Foreground = background, foreground
+		err = tmpl.ExecuteTemplate(f, templateName, data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+
+	for _, page := range pages {
+		title := page.String()
+		// The URL is escaped, while the files are named after the snippets.
+		name := path.Join("snippets", page.Folder, page.File+".html")
+		if err := write(name, "snippet.html", sitePage{Title: title, Root: "../../", Snippet: page}); err != nil {
+			return 0, err
+		}
+	}
+	err = write("index.html", "index.html", sitePage{
+		Title:   "Snippets",
+		Folders: siteGroups(folders),
+		Tags:    siteGroups(tags),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "search.json"), b, 0644); err != nil {
+		return 0, err
+	}
+	written["search.json"] = true
+	for _, name := range siteAssets {
+		b, err := fs.ReadFile(siteFiles, "site/"+name)
+		if err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+			return 0, err
+		}
+		written[name] = true
+	}
+
+	for _, name := range previous {
+		if !written[name] {
+			if err := removeSiteFile(dir, name); err != nil {
+				return 0, err
+			}
+		}
+	}
+	names := maps.Keys(written)
+	slices.Sort(names)
+	manifest := strings.Join(names, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, siteManifest), []byte(manifest), 0644); err != nil {
+		return 0, err
+	}
+	return len(pages), nil
+}
+
+// previousSite returns the files that the previous export wrote to the
+// directory. A directory without a previous export must be empty, so that
+// the export does not overwrite files it did not write.
+func previousSite(dir string) ([]string, error) {
+	manifest, err := os.ReadFile(filepath.Join(dir, siteManifest))
+	if err == nil {
+		var names []string
+		for _, name := range strings.Split(string(manifest), "\n") {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("%s: %w", dir, errNotSite)
+	}
+	return nil, nil
+}
+
+// removeSiteFile removes a file of the previous export along with the
+// directories that it leaves empty. Names that point outside of the site are
+// ignored.
+func removeSiteFile(dir, name string) error {
+	name = path.Clean(name)
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return nil
+	}
+	if err := removeFile(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+		return err
+	}
+	for d := path.Dir(name); d != "."; d = path.Dir(d) {
+		// Directories that still hold files are kept.
+		if os.Remove(filepath.Join(dir, filepath.FromSlash(d))) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// siteGroups returns the groups of snippets sorted by their names.
+func siteGroups(groups map[string][]*siteSnippet) []siteGroup {
+	names := maps.Keys(groups)
+	slices.SortFunc(names, func(a, b string) bool { return strings.ToLower(a) < strings.ToLower(b) })
+	result := make([]siteGroup, 0, len(names))
+	for _, name := range names {
+		result = append(result, siteGroup{name, groups[name]})
+	}
+	return result
+}
+
+// siteColors returns the background and foreground of the pages of the
+// site, taken from the chroma style of the theme so that they match the
+// highlighted snippets.
+func siteColors(config Config) (template.CSS, template.CSS) {
+	background, foreground := template.CSS("#ffffff"), template.CSS("#000000")
+	entry := styles.Get(config.chromaStyle()).Get(chroma.Background)
+	if entry.Background.IsSet() {
+		background = template.CSS(entry.Background.String())
+	}
+	if entry.Colour.IsSet() {
+		foreground = template.CSS(entry.Colour.String())
+	}
+	return background, foreground
+}
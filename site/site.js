@@ -0,0 +1,49 @@
+// Copies the contents of the element that the data-copy attribute of the
+// button refers to.
+document.addEventListener("click", (event) => {
+  const button = event.target.closest("button[data-copy]");
+  if (!button) {
+    return;
+  }
+  const source = document.getElementById(button.dataset.copy);
+  navigator.clipboard.writeText(source.value ?? source.textContent).then(() => {
+    const label = button.textContent;
+    button.textContent = "Copied";
+    setTimeout(() => (button.textContent = label), 1500);
+  });
+});
+
+// Searches the names, descriptions, tags and contents of the snippets in the
+// search index as the query is typed.
+const search = document.getElementById("search");
+if (search) {
+  const results = document.getElementById("results");
+  const library = document.getElementById("library");
+  const index = fetch(search.dataset.index).then((response) => response.json());
+
+  search.addEventListener("input", async () => {
+    const terms = search.value.toLowerCase().split(/\s+/).filter(Boolean);
+    results.replaceChildren();
+    library.hidden = terms.length > 0;
+    if (terms.length <= 0) {
+      return;
+    }
+    for (const snippet of await index) {
+      const text = [snippet.title, snippet.description, snippet.tags.join(" "), snippet.content]
+        .join("\n")
+        .toLowerCase();
+      if (!terms.every((term) => text.includes(term))) {
+        continue;
+      }
+      const item = document.createElement("li");
+      const link = document.createElement("a");
+      link.href = snippet.url;
+      link.textContent = snippet.title;
+      item.append(link);
+      if (snippet.description) {
+        item.append(" — " + snippet.description);
+      }
+      results.append(item);
+    }
+  });
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	agecrypt "filippo.io/age"
+)
+
+func TestExportSite(t *testing.T) {
+	config, snippets := testSnippets(t, map[string]string{
+		"shell/token.sh":      "export TOKEN=secret\n",
+		"misc/secret.txt.age": "not really encrypted",
+		".snp.yaml":           "shell/list.sh:\n  tags: [files]\n  description: Lists <all> files\nshell/token.sh:\n  sensitive: true\n",
+	})
+	dir := t.TempDir()
+	n, err := exportSite(config, snippets, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("exported %d snippets, want 4", n)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	index := read("index.html")
+	for _, want := range []string{
+		`<a href="snippets/shell/list.sh.html">list</a>`,
+		`<h3 id="tag-files">#files</h3>`,
+		"Lists &lt;all&gt; files",
+	} {
+		if !strings.Contains(index, want) {
+			t.Errorf("index.html does not contain %s", want)
+		}
+	}
+	if strings.Contains(index, "token") || strings.Contains(index, "secret") {
+		t.Error("index.html contains sensitive or encrypted snippets")
+	}
+
+	page := read("snippets/misc/hello.go.html")
+	for _, want := range []string{`<pre tabindex="0" style="`, `data-copy="content"`, `href="../../style.css"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("hello.go.html does not contain %s", want)
+		}
+	}
+
+	var entries []siteIndexEntry
+	if err := json.Unmarshal([]byte(read("search.json")), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[0].Title != "misc/empty.txt" || entries[0].URL != "snippets/misc/empty.txt.html" {
+		t.Errorf("unexpected search index: %+v", entries)
+	}
+	for _, asset := range siteAssets {
+		read(asset)
+	}
+
+	// Exporting again drops the pages of snippets that are no longer
+	// exported, but keeps the files that the export did not write.
+	if err := os.Remove(filepath.Join(config.Root, "misc", "hello.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "snippets", "misc", "notes.txt"), []byte("mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var kept []Snippet
+	for _, s := range snippets {
+		if s.File != "hello.go" {
+			kept = append(kept, s)
+		}
+	}
+	if _, err := exportSite(config, kept, dir, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "snippets", "misc", "hello.go.html")); !os.IsNotExist(err) {
+		t.Errorf("the page of a removed snippet was kept: %v", err)
+	}
+	read("snippets/shell/list.sh.html")
+	read("snippets/misc/notes.txt")
+
+	// Directories that hold other files are not exported into.
+	other := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(other, "snippets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exportSite(config, snippets, other, nil); !errors.Is(err, errNotSite) {
+		t.Errorf("exporting into a directory with other files: got error %v, want %v", err, errNotSite)
+	}
+	if _, err := os.Stat(filepath.Join(other, "snippets")); err != nil {
+		t.Errorf("the files in the directory were touched: %v", err)
+	}
+}
+
+func TestExportSiteEncrypted(t *testing.T) {
+	config, _ := testSnippets(t, nil)
+	identity, err := agecrypt.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := &keyring{
+		identities: []agecrypt.Identity{identity},
+		recipients: []agecrypt.Recipient{identity.Recipient()},
+		cache:      map[string]decrypted{},
+	}
+	if err := k.encrypt(filepath.Join(config.Root, "misc", "token.sh.age"), "export TOKEN=hunter2\n"); err != nil {
+		t.Fatal(err)
+	}
+	snippets := readSnippets(config)
+	page := filepath.Join("snippets", "misc", "token.sh.age.html")
+
+	// Encrypted snippets are only exported when they can be decrypted.
+	dir := t.TempDir()
+	if n, err := exportSite(config, snippets, dir, nil); err != nil || n != 4 {
+		t.Fatalf("exported %d snippets without a keyring: %v", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, page)); !os.IsNotExist(err) {
+		t.Errorf("the encrypted snippet was exported without a keyring: %v", err)
+	}
+
+	dir = t.TempDir()
+	if n, err := exportSite(config, snippets, dir, k); err != nil || n != 5 {
+		t.Fatalf("exported %d snippets with a keyring: %v", n, err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "hunter2") {
+		t.Error("the page of the encrypted snippet does not hold its decrypted contents")
+	}
+}
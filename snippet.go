@@ -2,11 +2,18 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
 	"github.com/alecthomas/chroma/v2/quick"
+	"github.com/alecthomas/chroma/v2/styles"
 )
 
 // TODO:
@@ -16,6 +23,10 @@ const defaultSnippetName = "Untitled"
 const defaultSnippetFileName = "snippet.txt"
 const defaultLanguage = "go"
 
+// encryptedExt is the extension that the files of encrypted snippets have
+// after the extension of their language.
+const encryptedExt = ".age"
+
 // defaultSnippet is a snippet with all of the default values, used for when
 // there are no snippets available.
 var defaultSnippet = Snippet{
@@ -32,6 +43,110 @@ type Snippet struct {
 	Name     string
 	File     string
 	Language string
+	Tags     []string
+	// Encrypted snippets are stored encrypted and are only readable once
+	// unlocked.
+	Encrypted bool
+	// Sensitive is whether the metadata marks the snippet as sensitive.
+	Sensitive bool
+}
+
+// newSnippet returns the snippet stored in the given file of the folder.
+// The language is taken from the file extension, which comes before the
+// extension of encrypted snippets.
+func newSnippet(folder, file string) Snippet {
+	base := strings.TrimSuffix(file, encryptedExt)
+	name := base
+	language := "txt"
+	if i := strings.LastIndex(base, "."); i > 0 {
+		name = base[:i]
+		language = base[i+1:]
+	}
+	return Snippet{Name: name, Folder: folder, File: file, Language: language, Encrypted: base != file}
+}
+
+// fileName returns the file name for the snippet with the name and language,
+// keeping it encrypted if it is.
+func (s Snippet) fileName(name, language string) string {
+	file := name + "." + language
+	if s.Encrypted {
+		file += encryptedExt
+	}
+	return file
+}
+
+// ignoredFile reports whether the file or folder should not be treated as a
+// snippet, such as hidden files and editor backups.
+func ignoredFile(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~")
+}
+
+// errSnippetExists is returned when a snippet is moved onto another snippet.
+var errSnippetExists = errors.New("snippet already exists")
+
+// collision is the way to handle moving a snippet onto another snippet.
+type collision int
+
+const (
+	failOnCollision collision = iota
+	overwriteOnCollision
+	suffixOnCollision
+)
+
+// moveSnippetFile moves the snippet file to the given folder and file name,
+// creating the folder if needed. If the file name is already taken, the
+// collision decides whether to fail, overwrite the other snippet or add a
+// numbered suffix to the file name.
+//
+// It returns the snippet at its new location.
+func moveSnippetFile(root string, s Snippet, folder, file string, c collision) (Snippet, error) {
+	if s.Folder == folder && s.File == file {
+		return s, nil
+	}
+	if err := validFolder(folder); err != nil {
+		return s, err
+	}
+	if file == "" || strings.ContainsAny(file, `/\`) || ignoredFile(file) {
+		return s, fmt.Errorf("invalid snippet name: %q", file)
+	}
+	if err := os.MkdirAll(filepath.Join(root, folder), 0755); err != nil {
+		return s, err
+	}
+	if _, err := os.Stat(filepath.Join(root, folder, file)); err == nil {
+		switch c {
+		case failOnCollision:
+			return s, fmt.Errorf("%w: %s/%s", errSnippetExists, folder, file)
+		case suffixOnCollision:
+			file = uniqueFile(root, folder, file)
+		}
+	}
+	if err := os.Rename(filepath.Join(root, s.Folder, s.File), filepath.Join(root, folder, file)); err != nil {
+		return s, err
+	}
+	err := updateLibrary(root, func(lib Library) {
+		lib.move(s.Folder, s.File, folder, file)
+	})
+	moved := newSnippet(folder, file)
+	moved.Tags = s.Tags
+	moved.Sensitive = s.Sensitive
+	return moved, err
+}
+
+// trashSnippetFile moves the snippet file to the trash of the snippet root.
+func trashSnippetFile(root string, s Snippet) error {
+	trash := filepath.Join(root, trashFolder, s.Folder)
+	if err := os.MkdirAll(trash, 0755); err != nil {
+		return err
+	}
+	// Snippets of the same name trashed within the same second receive a
+	// numbered suffix.
+	name := uniqueFile(root, filepath.Join(trashFolder, s.Folder), s.fileName(s.Name+"-"+time.Now().Format("20060102-150405"), s.Language))
+	if err := os.Rename(filepath.Join(root, s.Folder, s.File), filepath.Join(trash, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return updateLibrary(root, func(lib Library) {
+		delete(lib, metadataKey(s.Folder, s.File))
+	})
 }
 
 // String returns the folder/name.ext of the snippet.
@@ -39,23 +154,84 @@ func (s Snippet) String() string {
 	return fmt.Sprintf("%s/%s.%s", s.Folder, s.Name, s.Language)
 }
 
-// Content returns the snippet contents.
-func (s Snippet) Content(highlight bool) string {
+// Content returns the snippet contents. Encrypted snippets are decrypted with
+// the identity file, or with a passphrase that is asked for on the terminal.
+func (s Snippet) Content(highlight bool) (string, error) {
 	config := readConfig()
-	file := filepath.Join(config.Root, s.Folder, s.File)
-	content, err := os.ReadFile(file)
+	var k *keyring
+	if s.Encrypted {
+		var err error
+		if k, err = cliKeyring(config, false); err != nil {
+			return "", err
+		}
+	}
+	content, err := readSnippetFile(config, s, k)
 	if err != nil {
-		return ""
+		return "", err
 	}
 
 	if !highlight {
-		return string(content)
+		return content, nil
 	}
 
+	highlighted, err := highlightCode(content, s.Language, config)
+	if err != nil {
+		return content, nil
+	}
+	return highlighted, nil
+}
+
+// highlightCode returns the content highlighted as the language for the terminal.
+//
+// The content is returned as is when the terminal has no colors.
+func highlightCode(content, language string, config Config) (string, error) {
+	formatter := config.chromaFormatter()
+	if formatter == "" {
+		return content, nil
+	}
 	var b bytes.Buffer
-	err = quick.Highlight(&b, string(content), s.Language, "terminal16m", config.Theme)
+	err := quick.Highlight(&b, content, language, formatter, config.chromaStyle())
+	return b.String(), err
+}
+
+// highlightHTML returns the content highlighted as the language as an HTML
+// fragment with inline styles, in the chroma style of the theme.
+func highlightHTML(content, language string, config Config) (string, error) {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, content)
 	if err != nil {
-		return string(content)
+		return "", err
+	}
+	var b bytes.Buffer
+	formatter := html.New(html.WithClasses(false), html.TabWidth(config.Preview.tabWidth()))
+	err = formatter.Format(&b, styles.Get(config.chromaStyle()), iterator)
+	return b.String(), err
+}
+
+// detectLanguage returns the language of the content, judging by the file
+// name if there is one and otherwise by the content itself, such as a
+// shebang. It returns the fallback if the language cannot be detected.
+func detectLanguage(content, filename, fallback string) string {
+	var lexer chroma.Lexer
+	if filename != "" {
+		lexer = lexers.Match(filepath.Base(filename))
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(content)
+	}
+	if lexer == nil {
+		return fallback
+	}
+	for _, pattern := range lexer.Config().Filenames {
+		if ext := strings.TrimPrefix(pattern, "*."); ext != pattern && !strings.ContainsAny(ext, "*?[") {
+			return ext
+		}
+	}
+	if aliases := lexer.Config().Aliases; len(aliases) > 0 {
+		return aliases[0]
 	}
-	return b.String()
+	return fallback
 }
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// sortMode is the order of the snippets of a folder.
+type sortMode string
+
+const (
+	nameSort     sortMode = "name"
+	languageSort sortMode = "language"
+	modifiedSort sortMode = "modified"
+	createdSort  sortMode = "created"
+	sizeSort     sortMode = "size"
+	frecencySort sortMode = "frecency"
+)
+
+// sortModes are the sort modes in the order they are toggled through.
+var sortModes = []sortMode{nameSort, languageSort, modifiedSort, createdSort, sizeSort, frecencySort}
+
+var errUnknownSort = errors.New("unknown sort")
+
+// parseSort returns the sort mode with the name.
+func parseSort(name string) (sortMode, error) {
+	mode := sortMode(strings.ToLower(name))
+	if !slices.Contains(sortModes, mode) {
+		return "", fmt.Errorf("%w: %s, use one of %s", errUnknownSort, name, strings.Join(sortNames(), ", "))
+	}
+	return mode, nil
+}
+
+// sortNames returns the names of the sort modes.
+func sortNames() []string {
+	names := make([]string, len(sortModes))
+	for i, mode := range sortModes {
+		names[i] = string(mode)
+	}
+	return names
+}
+
+// next returns the sort mode after the mode.
+func (mode sortMode) next() sortMode {
+	i := slices.Index(sortModes, mode)
+	return sortModes[(i+1)%len(sortModes)]
+}
+
+// Sorts maps folders to the sort mode of their snippets. Folders without a
+// sort mode are sorted by name.
+type Sorts map[string]sortMode
+
+// sortsFile returns the path of the sort modes in the state directory.
+func sortsFile(stateDir string) string {
+	return filepath.Join(stateDir, "sorts.yaml")
+}
+
+// readSorts reads the sort modes of the folders from the state directory.
+func readSorts(stateDir string) (Sorts, error) {
+	sorts := Sorts{}
+	content, err := os.ReadFile(sortsFile(stateDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return sorts, nil
+	}
+	if err != nil {
+		return sorts, err
+	}
+	if err := yaml.Unmarshal(content, &sorts); err != nil {
+		return Sorts{}, err
+	}
+	return sorts, nil
+}
+
+// write writes the sort modes to the state directory.
+func (sorts Sorts) write(stateDir string) error {
+	content, err := yaml.Marshal(sorts)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(sortsFile(stateDir), content, 0644)
+}
+
+// mode returns the sort mode of the folder.
+func (sorts Sorts) mode(folder string) sortMode {
+	if mode, ok := sorts[folder]; ok && slices.Contains(sortModes, mode) {
+		return mode
+	}
+	return nameSort
+}
+
+// Use records how often and how recently a snippet was used.
+type Use struct {
+	Count int       `yaml:"count"`
+	Last  time.Time `yaml:"last"`
+}
+
+// frecency returns the score of the use, which weighs the number of uses by
+// how recent the last use was.
+func (u Use) frecency(now time.Time) float64 {
+	weight := 0.25
+	switch d := now.Sub(u.Last); {
+	case d < time.Hour:
+		weight = 4
+	case d < 24*time.Hour:
+		weight = 2
+	case d < 7*24*time.Hour:
+		weight = 1
+	case d < 30*24*time.Hour:
+		weight = 0.5
+	}
+	return float64(u.Count) * weight
+}
+
+// Usage maps the folder/file of snippets to their use.
+type Usage map[string]Use
+
+// usageFile returns the path of the usage in the state directory.
+func usageFile(stateDir string) string {
+	return filepath.Join(stateDir, "usage.yaml")
+}
+
+// readUsage reads the usage of the snippets from the state directory.
+func readUsage(stateDir string) (Usage, error) {
+	usage := Usage{}
+	content, err := os.ReadFile(usageFile(stateDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return usage, nil
+	}
+	if err != nil {
+		return usage, err
+	}
+	if err := yaml.Unmarshal(content, &usage); err != nil {
+		return Usage{}, err
+	}
+	return usage, nil
+}
+
+// usageMu serializes the updates of the usage, which are recorded from the
+// copy commands of the TUI and the requests of the API concurrently.
+var usageMu sync.Mutex
+
+// recordUse counts a use of the snippets in the usage in the state directory.
+// Uses that other processes record at the same time may be lost, as they do
+// not share the lock.
+func recordUse(stateDir string, snippets ...Snippet) error {
+	usageMu.Lock()
+	defer usageMu.Unlock()
+
+	usage, err := readUsage(stateDir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, s := range snippets {
+		key := metadataKey(s.Folder, s.File)
+		u := usage[key]
+		u.Count++
+		u.Last = now
+		usage[key] = u
+	}
+	content, err := yaml.Marshal(usage)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return err
+	}
+	return replaceFile(usageFile(stateDir), content, 0644)
+}
+
+// replaceFile writes the content to a temporary file next to the file and
+// renames it over the file, so that readers never see a partial write.
+func replaceFile(path string, content []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(content)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), perm)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// sortSnippets sorts the snippets by the mode. Times, sizes and frecency
+// sort the largest first, and snippets that compare equal keep their order.
+func sortSnippets(snippets []Snippet, mode sortMode, config Config) {
+	type fileStat struct {
+		size              int64
+		modified, created time.Time
+	}
+	stats := map[string]fileStat{}
+	stat := func(s Snippet) fileStat {
+		key := metadataKey(s.Folder, s.File)
+		if st, ok := stats[key]; ok {
+			return st
+		}
+		var st fileStat
+		path := filepath.Join(config.Root, s.Folder, s.File)
+		if fi, err := os.Stat(path); err == nil {
+			st = fileStat{fi.Size(), fi.ModTime(), birthTime(path, fi)}
+		}
+		stats[key] = st
+		return st
+	}
+	byName := func(a, b Snippet) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name) ||
+			(strings.EqualFold(a.Name, b.Name) && a.File < b.File)
+	}
+
+	var less func(a, b Snippet) bool
+	switch mode {
+	case languageSort:
+		less = func(a, b Snippet) bool {
+			return a.Language < b.Language || (a.Language == b.Language && byName(a, b))
+		}
+	case modifiedSort:
+		less = func(a, b Snippet) bool { return stat(a).modified.After(stat(b).modified) }
+	case createdSort:
+		less = func(a, b Snippet) bool { return stat(a).created.After(stat(b).created) }
+	case sizeSort:
+		less = func(a, b Snippet) bool { return stat(a).size > stat(b).size }
+	case frecencySort:
+		usage, _ := readUsage(config.StateDir)
+		now := time.Now()
+		less = func(a, b Snippet) bool {
+			return usage[metadataKey(a.Folder, a.File)].frecency(now) > usage[metadataKey(b.Folder, b.File)].frecency(now)
+		}
+	default:
+		less = byName
+	}
+	slices.SortStableFunc(snippets, less)
+}
+
+// sortList sorts the list of the folder by the sort mode of the folder,
+// keeping the selected snippet selected.
+func (m *Model) sortList(folder Folder) tea.Cmd {
+	li, ok := m.Lists[folder]
+	if !ok {
+		return nil
+	}
+	var snippets []Snippet
+	for _, item := range li.Items() {
+		if s, ok := item.(Snippet); ok {
+			snippets = append(snippets, s)
+		}
+	}
+	sortSnippets(snippets, m.sorts.mode(string(folder)), m.config)
+
+	selected, _ := li.SelectedItem().(Snippet)
+	items := make([]list.Item, len(snippets))
+	for i, s := range snippets {
+		items[i] = s
+	}
+	cmd := li.SetItems(items)
+	if i := indexOfSnippet(li, selected); i >= 0 {
+		li.Select(i)
+	}
+	return cmd
+}
+
+// toggleSort switches the selected folder to the next sort mode and
+// remembers it.
+func (m *Model) toggleSort() tea.Cmd {
+	folder := m.selectedFolder()
+	m.sorts[string(folder)] = m.sorts.mode(string(folder)).next()
+	sorts := maps.Clone(m.sorts)
+	return tea.Batch(m.sortList(folder), m.updateContent(), func() tea.Msg {
+		if err := sorts.write(m.config.StateDir); err != nil {
+			return errorMsg{err}
+		}
+		return nil
+	})
+}
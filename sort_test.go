@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestRecordUse(t *testing.T) {
+	stateDir := t.TempDir()
+	hello, list := newSnippet("misc", "hello.go"), newSnippet("shell", "list.sh")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := recordUse(stateDir, hello, list); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	usage, err := readUsage(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"misc/hello.go", "shell/list.sh"} {
+		if usage[key].Count != 20 {
+			t.Errorf("%s: got %d uses, want 20", key, usage[key].Count)
+		}
+	}
+	// Only the usage is left in the state directory.
+	if entries, err := os.ReadDir(stateDir); err != nil || len(entries) != 1 {
+		t.Errorf("got %d entries in the state directory, %v", len(entries), err)
+	}
+}
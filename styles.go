@@ -39,16 +39,18 @@ type SnippetsBaseStyle struct {
 	DeletedTitleBar    lipgloss.Style
 	DeletedTitle       lipgloss.Style
 	DeletedSubtitle    lipgloss.Style
+	Mark               lipgloss.Style
 }
 
 // FoldersBaseStyle holds the neccessary styling for the folders pane of
 // the application.
 type FoldersBaseStyle struct {
-	Base       lipgloss.Style
-	Title      lipgloss.Style
-	TitleBar   lipgloss.Style
-	Selected   lipgloss.Style
-	Unselected lipgloss.Style
+	Base            lipgloss.Style
+	Title           lipgloss.Style
+	TitleBar        lipgloss.Style
+	DeletedTitleBar lipgloss.Style
+	Selected        lipgloss.Style
+	Unselected      lipgloss.Style
 }
 
 // ContentBaseStyle holds the neccessary styling for the content pane of the
@@ -60,6 +62,7 @@ type ContentBaseStyle struct {
 	LineNumber   lipgloss.Style
 	EmptyHint    lipgloss.Style
 	EmptyHintKey lipgloss.Style
+	Diagnostic   lipgloss.Style
 }
 
 // Styles is the struct of all styles for the application.
@@ -74,23 +77,24 @@ var marginStyle = lipgloss.NewStyle().Margin(1, 0, 0, 1)
 // DefaultStyles is the default implementation of the styles struct for all
 // styling in the application.
 func DefaultStyles(config Config) Styles {
-	white := lipgloss.Color(config.ForegroundColor)
-	black := lipgloss.Color(config.BackgroundColor)
-	red := lipgloss.Color(config.RedColor)
-	green := lipgloss.Color(config.GreenColor)
-	// yellow := lipgloss.Color(config.YellowColor)
-	blue := lipgloss.Color(config.BlueColor)
-	// magenta := lipgloss.Color(config.MagentaColor)
-	// cyan := lipgloss.Color(config.CyanColor)
-	brightRed := lipgloss.Color(config.BrightRedColor)
-	brightGreen := lipgloss.Color(config.BrightGreenColor)
-	// brightYellow := lipgloss.Color(config.BrightYellowColor)
-	brightBlue := lipgloss.Color(config.BrightBlueColor)
-	// brightMagenta := lipgloss.Color(config.BrightMagentaColor)
-	// brightCyan := lipgloss.Color(config.BrightCyanColor)
-	gray := lipgloss.Color(config.GrayColor)
+	palette := config.palette()
+	white := lipgloss.Color(palette.Foreground)
+	black := lipgloss.Color(palette.Background)
+	red := lipgloss.Color(palette.Red)
+	green := lipgloss.Color(palette.Green)
+	yellow := lipgloss.Color(palette.Yellow)
+	blue := lipgloss.Color(palette.Blue)
+	// magenta := lipgloss.Color(palette.Magenta)
+	// cyan := lipgloss.Color(palette.Cyan)
+	brightRed := lipgloss.Color(palette.BrightRed)
+	brightGreen := lipgloss.Color(palette.BrightGreen)
+	// brightYellow := lipgloss.Color(palette.BrightYellow)
+	brightBlue := lipgloss.Color(palette.BrightBlue)
+	// brightMagenta := lipgloss.Color(palette.BrightMagenta)
+	// brightCyan := lipgloss.Color(palette.BrightCyan)
+	gray := lipgloss.Color(palette.Gray)
 
-	return Styles{
+	styles := Styles{
 		Snippets: SnippetsStyle{
 			Focused: SnippetsBaseStyle{
 				Base:               lipgloss.NewStyle().Width(35),
@@ -105,6 +109,7 @@ func DefaultStyles(config Config) Styles {
 				DeletedTitleBar:    lipgloss.NewStyle().Background(red).Width(35-2).Margin(0, 1, 1, 1).Padding(0, 1).Foreground(white),
 				DeletedTitle:       lipgloss.NewStyle().Foreground(brightRed),
 				DeletedSubtitle:    lipgloss.NewStyle().Foreground(red),
+				Mark:               lipgloss.NewStyle().Foreground(yellow),
 			},
 			Blurred: SnippetsBaseStyle{
 				Base:               lipgloss.NewStyle().Width(35),
@@ -119,22 +124,25 @@ func DefaultStyles(config Config) Styles {
 				DeletedTitleBar:    lipgloss.NewStyle().Background(red).Width(35-2).Margin(0, 1, 1, 1).Padding(0, 1),
 				DeletedTitle:       lipgloss.NewStyle().Foreground(brightRed),
 				DeletedSubtitle:    lipgloss.NewStyle().Foreground(red),
+				Mark:               lipgloss.NewStyle().Foreground(yellow),
 			},
 		},
 		Folders: FoldersStyle{
 			Focused: FoldersBaseStyle{
-				Base:       lipgloss.NewStyle().Width(22),
-				Title:      lipgloss.NewStyle().Padding(0, 1).Foreground(white),
-				TitleBar:   lipgloss.NewStyle().Background(blue).Width(22-2).Margin(0, 1, 1, 1),
-				Selected:   lipgloss.NewStyle().Foreground(brightBlue),
-				Unselected: lipgloss.NewStyle().Foreground(gray),
+				Base:            lipgloss.NewStyle().Width(22),
+				Title:           lipgloss.NewStyle().Padding(0, 1).Foreground(white),
+				TitleBar:        lipgloss.NewStyle().Background(blue).Width(22-2).Margin(0, 1, 1, 1),
+				DeletedTitleBar: lipgloss.NewStyle().Background(red).Width(22-2).Margin(0, 1, 1, 1),
+				Selected:        lipgloss.NewStyle().Foreground(brightBlue),
+				Unselected:      lipgloss.NewStyle().Foreground(gray),
 			},
 			Blurred: FoldersBaseStyle{
-				Base:       lipgloss.NewStyle().Width(22),
-				Title:      lipgloss.NewStyle().Padding(0, 1).Foreground(gray),
-				TitleBar:   lipgloss.NewStyle().Background(black).Width(22-2).Margin(0, 1, 1, 1),
-				Selected:   lipgloss.NewStyle().Foreground(brightBlue),
-				Unselected: lipgloss.NewStyle().Foreground(lipgloss.Color("237")),
+				Base:            lipgloss.NewStyle().Width(22),
+				Title:           lipgloss.NewStyle().Padding(0, 1).Foreground(gray),
+				TitleBar:        lipgloss.NewStyle().Background(black).Width(22-2).Margin(0, 1, 1, 1),
+				DeletedTitleBar: lipgloss.NewStyle().Background(red).Width(22-2).Margin(0, 1, 1, 1),
+				Selected:        lipgloss.NewStyle().Foreground(brightBlue),
+				Unselected:      lipgloss.NewStyle().Foreground(lipgloss.Color("237")),
 			},
 		},
 		Content: ContentStyle{
@@ -145,6 +153,7 @@ func DefaultStyles(config Config) Styles {
 				LineNumber:   lipgloss.NewStyle().Foreground(gray),
 				EmptyHint:    lipgloss.NewStyle().Foreground(gray),
 				EmptyHintKey: lipgloss.NewStyle().Foreground(brightBlue),
+				Diagnostic:   lipgloss.NewStyle().Foreground(yellow),
 			},
 			Blurred: ContentBaseStyle{
 				Base:         lipgloss.NewStyle().Margin(0, 1),
@@ -153,7 +162,31 @@ func DefaultStyles(config Config) Styles {
 				LineNumber:   lipgloss.NewStyle().Foreground(black),
 				EmptyHint:    lipgloss.NewStyle().Foreground(gray),
 				EmptyHintKey: lipgloss.NewStyle().Foreground(brightBlue),
+				Diagnostic:   lipgloss.NewStyle().Foreground(yellow),
 			},
 		},
 	}
+	_ = config.theme.apply(&styles, palette.colors())
+	return styles
+}
+
+// resize returns the styles with the folder and snippet panes sized to the
+// widths. Panes without a width keep their size.
+func (s Styles) resize(folders, snippets int) Styles {
+	if snippets > 0 {
+		for _, style := range []*SnippetsBaseStyle{&s.Snippets.Focused, &s.Snippets.Blurred} {
+			style.Base = style.Base.Width(snippets)
+			style.TitleBar = style.TitleBar.Width(snippets - 2)
+			style.CopiedTitleBar = style.CopiedTitleBar.Width(snippets - 2)
+			style.DeletedTitleBar = style.DeletedTitleBar.Width(snippets - 2)
+		}
+	}
+	if folders > 0 {
+		for _, style := range []*FoldersBaseStyle{&s.Folders.Focused, &s.Folders.Blurred} {
+			style.Base = style.Base.Width(folders)
+			style.TitleBar = style.TitleBar.Width(folders - 2)
+			style.DeletedTitleBar = style.DeletedTitleBar.Width(folders - 2)
+		}
+	}
+	return s
 }
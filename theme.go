@@ -0,0 +1,377 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// bundledThemes holds the themes that ship with snp.
+//
+//go:embed themes/*.yaml
+var bundledThemes embed.FS
+
+// defaultTheme is the theme that uses the colors of the terminal.
+const defaultTheme = "default"
+
+var errUnknownTheme = errors.New("unknown theme")
+
+// Palette holds the colors of a theme. Colors that are set in the config take
+// precedence over the colors of the theme.
+type Palette struct {
+	Foreground    string `yaml:"foreground"`
+	Background    string `yaml:"background"`
+	Red           string `yaml:"red"`
+	Green         string `yaml:"green"`
+	Yellow        string `yaml:"yellow"`
+	Blue          string `yaml:"blue"`
+	Magenta       string `yaml:"magenta"`
+	Cyan          string `yaml:"cyan"`
+	BrightRed     string `yaml:"bright_red"`
+	BrightGreen   string `yaml:"bright_green"`
+	BrightYellow  string `yaml:"bright_yellow"`
+	BrightBlue    string `yaml:"bright_blue"`
+	BrightMagenta string `yaml:"bright_magenta"`
+	BrightCyan    string `yaml:"bright_cyan"`
+	Gray          string `yaml:"gray"`
+}
+
+// defaultPalette holds the colors of the terminal, which are used for the
+// colors that neither the config nor the theme sets.
+var defaultPalette = Palette{
+	Foreground:    "15",
+	Background:    "0",
+	Red:           "1",
+	Green:         "2",
+	Yellow:        "3",
+	Blue:          "4",
+	Magenta:       "5",
+	Cyan:          "6",
+	BrightRed:     "9",
+	BrightGreen:   "10",
+	BrightYellow:  "11",
+	BrightBlue:    "12",
+	BrightMagenta: "13",
+	BrightCyan:    "14",
+	Gray:          "7",
+}
+
+// colors returns the colors of the palette by their names in the theme file.
+func (p Palette) colors() map[string]string {
+	colors := map[string]string{}
+	v := reflect.ValueOf(p)
+	for i := 0; i < v.NumField(); i++ {
+		colors[v.Type().Field(i).Tag.Get("yaml")] = v.Field(i).String()
+	}
+	return colors
+}
+
+// StyleOverride changes the style of a single element. Colors may be given as
+// names of the palette.
+type StyleOverride struct {
+	Foreground string `yaml:"foreground"`
+	Background string `yaml:"background"`
+	Bold       bool   `yaml:"bold"`
+	Italic     bool   `yaml:"italic"`
+	Underline  bool   `yaml:"underline"`
+}
+
+// apply returns the style with the override applied.
+func (o StyleOverride) apply(style lipgloss.Style, colors map[string]string) lipgloss.Style {
+	color := func(c string) lipgloss.Color {
+		if named, ok := colors[c]; ok {
+			return lipgloss.Color(named)
+		}
+		return lipgloss.Color(c)
+	}
+	if o.Foreground != "" {
+		style = style.Foreground(color(o.Foreground))
+	}
+	if o.Background != "" {
+		style = style.Background(color(o.Background))
+	}
+	if o.Bold {
+		style = style.Bold(true)
+	}
+	if o.Italic {
+		style = style.Italic(true)
+	}
+	if o.Underline {
+		style = style.Underline(true)
+	}
+	return style
+}
+
+// PaneOverrides holds the overrides of the elements of a pane by their snake
+// cased names, such as selected_title, when focused and blurred.
+type PaneOverrides struct {
+	Focused map[string]StyleOverride `yaml:"focused"`
+	Blurred map[string]StyleOverride `yaml:"blurred"`
+}
+
+// Theme is a named UI theme that pairs a palette with a chroma style to
+// highlight snippets with.
+//
+// Example:
+//
+//	chroma: nord
+//	palette:
+//	  blue: "#5e81ac"
+//	snippets:
+//	  focused:
+//	    selected_title: { foreground: bright_cyan, bold: true }
+type Theme struct {
+	Name     string        `yaml:"-"`
+	Chroma   string        `yaml:"chroma"`
+	Palette  Palette       `yaml:"palette"`
+	Snippets PaneOverrides `yaml:"snippets"`
+	Folders  PaneOverrides `yaml:"folders"`
+	Content  PaneOverrides `yaml:"content"`
+}
+
+// themesDir returns the directory that theme files are read from.
+func themesDir() string {
+	return filepath.Join(filepath.Dir(defaultConfig()), "themes")
+}
+
+// loadTheme reads the theme with the name from the themes directory, or else
+// from the bundled themes.
+func loadTheme(name string) (Theme, error) {
+	b, err := os.ReadFile(filepath.Join(themesDir(), name+".yaml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		b, err = bundledThemes.ReadFile("themes/" + name + ".yaml")
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return Theme{}, fmt.Errorf("%w: %s", errUnknownTheme, name)
+	}
+	if err != nil {
+		return Theme{}, err
+	}
+
+	t := Theme{Name: name}
+	if err := yaml.Unmarshal(b, &t); err != nil {
+		return Theme{}, fmt.Errorf("theme %s: %w", name, err)
+	}
+	if err := t.validate(); err != nil {
+		return Theme{}, fmt.Errorf("theme %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// listThemes returns the names of the bundled themes and the themes in the
+// themes directory.
+func listThemes() []string {
+	names := map[string]bool{}
+	for _, dir := range []fs.FS{bundledThemes, os.DirFS(filepath.Dir(themesDir()))} {
+		entries, _ := fs.ReadDir(dir, "themes")
+		for _, e := range entries {
+			if name := strings.TrimSuffix(e.Name(), ".yaml"); !e.IsDir() && name != e.Name() {
+				names[name] = true
+			}
+		}
+	}
+	themes := maps.Keys(names)
+	slices.Sort(themes)
+	return themes
+}
+
+// snakeCase returns the field name in snake case, as used in theme files.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// applyOverrides applies the overrides to the style fields of the struct that
+// base points to. It returns an error for overrides of unknown elements.
+func applyOverrides(base interface{}, overrides map[string]StyleOverride, colors map[string]string) error {
+	v := reflect.ValueOf(base).Elem()
+	known := map[string]bool{}
+	for i := 0; i < v.NumField(); i++ {
+		name := snakeCase(v.Type().Field(i).Name)
+		known[name] = true
+		if o, ok := overrides[name]; ok {
+			style := v.Field(i).Interface().(lipgloss.Style)
+			v.Field(i).Set(reflect.ValueOf(o.apply(style, colors)))
+		}
+	}
+	for name := range overrides {
+		if !known[name] {
+			return fmt.Errorf("unknown element: %s", name)
+		}
+	}
+	return nil
+}
+
+// apply applies the overrides of the theme to the styles.
+func (t Theme) apply(styles *Styles, colors map[string]string) error {
+	for _, err := range []error{
+		applyOverrides(&styles.Snippets.Focused, t.Snippets.Focused, colors),
+		applyOverrides(&styles.Snippets.Blurred, t.Snippets.Blurred, colors),
+		applyOverrides(&styles.Folders.Focused, t.Folders.Focused, colors),
+		applyOverrides(&styles.Folders.Blurred, t.Folders.Blurred, colors),
+		applyOverrides(&styles.Content.Focused, t.Content.Focused, colors),
+		applyOverrides(&styles.Content.Blurred, t.Content.Blurred, colors),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate returns an error if the theme overrides unknown elements.
+func (t Theme) validate() error {
+	var styles Styles
+	return t.apply(&styles, nil)
+}
+
+// withTheme returns the config using the theme with the name. A name that is
+// not a theme is taken as the name of a chroma style, as older configs did.
+func (c Config) withTheme(name string) (Config, error) {
+	t, err := loadTheme(name)
+	if errors.Is(err, errUnknownTheme) {
+		t, err = Theme{Name: name, Chroma: name}, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	c.Theme = name
+	c.theme = t
+	return c, nil
+}
+
+// palette returns the colors of the config, falling back to the colors of
+// its theme and then to the colors of the terminal for the colors that are
+// not set.
+func (c Config) palette() Palette {
+	configured := Palette{
+		Foreground:    c.ForegroundColor,
+		Background:    c.BackgroundColor,
+		Red:           c.RedColor,
+		Green:         c.GreenColor,
+		Yellow:        c.YellowColor,
+		Blue:          c.BlueColor,
+		Magenta:       c.MagentaColor,
+		Cyan:          c.CyanColor,
+		BrightRed:     c.BrightRedColor,
+		BrightGreen:   c.BrightGreenColor,
+		BrightYellow:  c.BrightYellowColor,
+		BrightBlue:    c.BrightBlueColor,
+		BrightMagenta: c.BrightMagentaColor,
+		BrightCyan:    c.BrightCyanColor,
+		Gray:          c.GrayColor,
+	}
+	p := defaultPalette
+	v := reflect.ValueOf(&p).Elem()
+	for _, colors := range []Palette{c.theme.Palette, configured} {
+		layer := reflect.ValueOf(colors)
+		for i := 0; i < v.NumField(); i++ {
+			if color := layer.Field(i).String(); color != "" {
+				v.Field(i).SetString(color)
+			}
+		}
+	}
+	return p
+}
+
+// chromaStyle returns the chroma style to highlight snippets with.
+func (c Config) chromaStyle() string {
+	if c.theme.Chroma != "" {
+		return c.theme.Chroma
+	}
+	return c.Theme
+}
+
+// nextTheme switches to the theme after the current one and restyles the
+// application.
+func (m *Model) nextTheme() tea.Cmd {
+	themes := listThemes()
+	if len(themes) <= 0 {
+		return nil
+	}
+	next := themes[0]
+	if i := slices.Index(themes, m.config.Theme); i >= 0 && i+1 < len(themes) {
+		next = themes[i+1]
+	}
+	config, err := m.config.withTheme(next)
+	if err != nil {
+		m.displayError(err.Error())
+		return nil
+	}
+	m.config = config
+	m.updateStyles()
+	return m.updateContent()
+}
+
+// themePreviewCode is the snippet that themes are previewed with.
+const themePreviewCode = `package main
+
+// greet says hello.
+func greet(name string) string {
+	return "hello, " + name
+}
+`
+
+// themePreview returns a sample of the panes and a highlighted snippet in the
+// theme of the config.
+func themePreview(config Config) string {
+	styles := DefaultStyles(config)
+	snippets, folders, content := styles.Snippets.Focused, styles.Folders.Blurred, styles.Content.Blurred
+
+	var swatches []string
+	palette := config.palette()
+	colors := palette.colors()
+	for _, name := range []string{"foreground", "background", "red", "green", "yellow", "blue", "magenta", "cyan", "gray"} {
+		swatches = append(swatches, lipgloss.NewStyle().Background(lipgloss.Color(colors[name])).Render("   "))
+	}
+
+	code, err := highlightCode(themePreviewCode, "go", config)
+	if err != nil {
+		code = themePreviewCode
+	}
+	return lipgloss.JoinVertical(lipgloss.Left,
+		fmt.Sprintf("%s (chroma: %s)", config.Theme, config.chromaStyle()),
+		strings.Join(swatches, ""),
+		"",
+		lipgloss.JoinHorizontal(lipgloss.Top,
+			folders.Base.Render(lipgloss.JoinVertical(lipgloss.Left,
+				folders.TitleBar.Render(folders.Title.Render("Folders")),
+				folders.Selected.Render("→ misc"),
+				folders.Unselected.Render("  notes"),
+			)),
+			snippets.Base.Render(lipgloss.JoinVertical(lipgloss.Left,
+				snippets.TitleBar.Render("Snippets"),
+				snippets.SelectedTitle.Render("→ greet"),
+				snippets.SelectedSubtitle.Render("  misc • go"),
+				"",
+				snippets.UnselectedTitle.Render("  notes"),
+				snippets.UnselectedSubtitle.Render("  misc • md"),
+				"",
+				snippets.CopiedTitleBar.Render("Copied Snippet!"),
+				snippets.DeletedTitleBar.Render("Delete Snippet? (y/N)"),
+			)),
+			lipgloss.JoinVertical(lipgloss.Left,
+				content.Title.Render("greet.go"),
+				content.Base.Render(code),
+			),
+		),
+	) + "\n"
+}
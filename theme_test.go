@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// testThemes points the themes directory at a temporary directory with the
+// theme files.
+func testThemes(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("SNP_CONFIG", filepath.Join(dir, "config.yaml"))
+	if err := os.Mkdir(themesDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(themesDir(), name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	testThemes(t, map[string]string{
+		"custom.yaml":  "chroma: monokai\npalette:\n  red: \"#ff0000\"\nsnippets:\n  focused:\n    selected_title: { foreground: red }\n",
+		"gruvbox.yaml": "chroma: gruvbox-light\n",
+		"broken.yaml":  "snippets:\n  focused:\n    nope: { bold: true }\n",
+	})
+
+	tests := []struct {
+		name   string
+		chroma string
+		red    string
+		err    bool
+	}{
+		{"nord", "nord", "#bf616a", false},
+		{"custom", "monokai", "#ff0000", false},
+		// Themes in the themes directory take precedence over bundled ones.
+		{"gruvbox", "gruvbox-light", "", false},
+		{"broken", "", "", true},
+	}
+	for _, tt := range tests {
+		theme, err := loadTheme(tt.name)
+		if (err != nil) != tt.err {
+			t.Errorf("loadTheme(%s) error = %v, want error %t", tt.name, err, tt.err)
+			continue
+		}
+		if theme.Chroma != tt.chroma || theme.Palette.Red != tt.red {
+			t.Errorf("loadTheme(%s) = chroma %s and red %q, want chroma %s and red %q", tt.name, theme.Chroma, theme.Palette.Red, tt.chroma, tt.red)
+		}
+	}
+
+	if _, err := loadTheme("nope"); !errors.Is(err, errUnknownTheme) {
+		t.Errorf("loadTheme(nope) error = %v, want %v", err, errUnknownTheme)
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	var styles SnippetsBaseStyle
+	overrides := map[string]StyleOverride{
+		"selected_title": {Foreground: "red", Bold: true},
+		"mark":           {Background: "#123456", Underline: true},
+	}
+	if err := applyOverrides(&styles, overrides, map[string]string{"red": "#ff0000"}); err != nil {
+		t.Fatal(err)
+	}
+	if styles.SelectedTitle.GetForeground() != lipgloss.Color("#ff0000") || !styles.SelectedTitle.GetBold() {
+		t.Errorf("selected title: got foreground %v and bold %t", styles.SelectedTitle.GetForeground(), styles.SelectedTitle.GetBold())
+	}
+	if styles.Mark.GetBackground() != lipgloss.Color("#123456") || !styles.Mark.GetUnderline() {
+		t.Errorf("mark: got background %v and underline %t", styles.Mark.GetBackground(), styles.Mark.GetUnderline())
+	}
+	if styles.Title.GetBold() {
+		t.Error("title was changed without an override")
+	}
+
+	err := applyOverrides(&styles, map[string]StyleOverride{"nope": {Bold: true}}, nil)
+	if err == nil {
+		t.Error("applyOverrides accepted an unknown element")
+	}
+}
+
+func TestPalette(t *testing.T) {
+	testThemes(t, nil)
+
+	if p := newConfig().palette(); p != defaultPalette {
+		t.Errorf("default theme: got %+v, want %+v", p, defaultPalette)
+	}
+
+	config, err := newConfig().withTheme("nord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.RedColor = "#000001"
+	config.GrayColor = "8"
+	p := config.palette()
+	// Colors set in the config take precedence over the colors of the
+	// theme.
+	if p.Red != "#000001" || p.Gray != "8" {
+		t.Errorf("configured colors: got red %s and gray %s", p.Red, p.Gray)
+	}
+	if p.Blue != "#5e81ac" || p.Foreground != "#eceff4" {
+		t.Errorf("theme colors: got blue %s and foreground %s", p.Blue, p.Foreground)
+	}
+}
+
+func TestLightTheme(t *testing.T) {
+	tests := []struct {
+		theme, lightTheme string
+		want              string
+	}{
+		{defaultTheme, "", defaultLightTheme},
+		{defaultTheme, "nord", "nord"},
+		// A theme that was set keeps being used on light backgrounds, unless
+		// a light theme was set too.
+		{"dracula", "", ""},
+		{"dracula", defaultLightTheme, defaultLightTheme},
+	}
+	for _, tt := range tests {
+		config := newConfig()
+		config.Theme, config.LightTheme = tt.theme, tt.lightTheme
+		if got := config.lightTheme(); got != tt.want {
+			t.Errorf("lightTheme() with theme %q and light theme %q = %q, want %q", tt.theme, tt.lightTheme, got, tt.want)
+		}
+	}
+}
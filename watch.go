@@ -0,0 +1,198 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+)
+
+// snippetsAddedMsg tells the application that snippet files were created
+// outside of the application.
+type snippetsAddedMsg []Snippet
+
+// snippetsRemovedMsg tells the application that snippet files were removed
+// outside of the application.
+type snippetsRemovedMsg []Snippet
+
+// folderRemovedMsg tells the application that a whole folder, or a snippet
+// at the root of the snippets, was removed outside of the application.
+type folderRemovedMsg Folder
+
+// snippetChangedMsg tells the application that the contents of a snippet file
+// changed outside of the application.
+type snippetChangedMsg Snippet
+
+// newWatcher returns a watcher for the snippet root and all of its folders.
+func newWatcher(root string) (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Add(root); err != nil {
+		w.Close()
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return w, nil
+	}
+	for _, e := range entries {
+		if e.IsDir() && !ignoredFile(e.Name()) {
+			_ = w.Add(filepath.Join(root, e.Name()))
+		}
+	}
+	return w, nil
+}
+
+// watch returns a Cmd that waits for the next change in the snippet root and
+// translates it into a message for the application.
+//
+// The application must call watch again after handling the message to keep
+// receiving changes.
+func (m *Model) watch() tea.Cmd {
+	if m.watcher == nil {
+		return nil
+	}
+	w := m.watcher
+	root := m.config.Root
+	return func() tea.Msg {
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return nil
+				}
+				if msg := watchEventMsg(w, root, event); msg != nil {
+					return msg
+				}
+			case _, ok := <-w.Errors:
+				if !ok {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// watchEventMsg returns the message for the file system event, or nil if the
+// event does not concern any snippet.
+func watchEventMsg(w *fsnotify.Watcher, root string, event fsnotify.Event) tea.Msg {
+	rel, err := filepath.Rel(root, event.Name)
+	if err != nil {
+		return nil
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for _, p := range parts {
+		if ignoredFile(p) {
+			return nil
+		}
+	}
+
+	var folder, file string
+	switch len(parts) {
+	case 1:
+		folder, file = defaultSnippetFolder, parts[0]
+	case 2:
+		folder, file = parts[0], parts[1]
+	default:
+		return nil
+	}
+
+	switch {
+	case event.Has(fsnotify.Create):
+		fi, err := os.Stat(event.Name)
+		if err != nil {
+			return nil
+		}
+		if !fi.IsDir() {
+			return snippetsAddedMsg{newSnippet(folder, file)}
+		}
+		if len(parts) != 1 {
+			return nil
+		}
+		// The folder may have been moved in with its snippets already in place.
+		_ = w.Add(event.Name)
+		entries, err := os.ReadDir(event.Name)
+		if err != nil {
+			return nil
+		}
+		var snippets snippetsAddedMsg
+		for _, e := range entries {
+			if !e.IsDir() && !ignoredFile(e.Name()) {
+				snippets = append(snippets, newSnippet(parts[0], e.Name()))
+			}
+		}
+		return snippets
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		// The entry is gone, so there is no telling whether a root entry was a
+		// folder or a snippet. The application knows which folders exist.
+		if len(parts) == 1 {
+			return folderRemovedMsg(file)
+		}
+		return snippetsRemovedMsg{newSnippet(folder, file)}
+	case event.Has(fsnotify.Write):
+		return snippetChangedMsg(newSnippet(folder, file))
+	}
+	return nil
+}
+
+// indexOfSnippet returns the index of the snippet in the list, or -1 if the
+// list does not contain the snippet.
+func indexOfSnippet(li *list.Model, s Snippet) int {
+	for i, item := range li.Items() {
+		if snippet, ok := item.(Snippet); ok && snippet.Folder == s.Folder && snippet.File == s.File {
+			return i
+		}
+	}
+	return -1
+}
+
+// addSnippets adds the snippets to the lists of their folders, creating the
+// folders as needed.
+func (m *Model) addSnippets(snippets []Snippet) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, s := range snippets {
+		li, ok := m.Lists[Folder(s.Folder)]
+		if !ok {
+			li = newList([]list.Item{}, m.height, m.ListStyle)
+			m.Lists[Folder(s.Folder)] = li
+		}
+		if indexOfSnippet(li, s) >= 0 {
+			continue
+		}
+		cmds = append(cmds, li.InsertItem(len(li.Items()), s), m.sortList(Folder(s.Folder)))
+	}
+	cmds = append(cmds, m.setFolders(m.updateFoldersView()), m.updateContent())
+	return tea.Batch(cmds...)
+}
+
+// removeSnippets removes the snippets from the lists of their folders.
+func (m *Model) removeSnippets(snippets []Snippet) tea.Cmd {
+	for _, s := range snippets {
+		li, ok := m.Lists[Folder(s.Folder)]
+		if !ok {
+			continue
+		}
+		if i := indexOfSnippet(li, s); i >= 0 {
+			li.RemoveItem(i)
+		}
+	}
+	return m.updateContent()
+}
+
+// removeFolder removes the folder and all of its snippets. If there is no such
+// folder, the name refers to a snippet at the root of the snippets.
+func (m *Model) removeFolder(folder Folder) tea.Cmd {
+	if _, ok := m.Lists[folder]; !ok {
+		return m.removeSnippets([]Snippet{newSnippet(defaultSnippetFolder, string(folder))})
+	}
+	delete(m.Lists, folder)
+	if len(m.Lists) <= 0 {
+		m.Lists[defaultSnippetFolder] = newList([]list.Item{}, m.height, m.ListStyle)
+	}
+	return tea.Batch(m.setFolders(m.updateFoldersView()), m.updateContent())
+}